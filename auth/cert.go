@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+)
+
+// FromCertificate returns the identity of a verified client certificate: its
+// common name is the subject, and its organizational units are the roles
+func FromCertificate(cert *x509.Certificate) (Identity, error) {
+	if cert.Subject.CommonName == "" {
+		return Identity{}, fmt.Errorf("%w: client certificate has no common name", ErrUnauthenticated)
+	}
+
+	return Identity{
+		Subject: cert.Subject.CommonName,
+		Roles:   cert.Subject.OrganizationalUnit,
+	}, nil
+}
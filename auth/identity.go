@@ -0,0 +1,24 @@
+// Package auth authenticates callers of the ticket service and carries their
+// identity through request contexts
+package auth
+
+import "context"
+
+// Identity is an authenticated caller
+type Identity struct {
+	Subject string   // stable caller ID, used as actor and reporter
+	Roles   []string // roles granted by the token, if any
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the caller's identity
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored by WithIdentity, if any
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jsonWebKey is a single entry of a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+
+	// RSA public key
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// Symmetric key
+	K string `json:"k"`
+}
+
+// verificationKey is a key that can check token signatures
+type verificationKey struct {
+	id  string
+	alg string      // HS256 or RS256
+	key interface{} // []byte for HS256, *rsa.PublicKey for RS256
+}
+
+// KeySet holds the keys trusted to sign tokens
+type KeySet struct {
+	keys []verificationKey
+}
+
+// LoadJWKS reads a JWKS file containing RSA ("RSA") and symmetric ("oct") keys.
+// Keys meant for encryption rather than signatures are skipped.
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	set := &KeySet{}
+	for i, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJWK(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %d (kid %q): %w", i, jwk.Kid, err)
+		}
+		set.keys = append(set.keys, key)
+	}
+
+	if len(set.keys) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+
+	return set, nil
+}
+
+func parseJWK(jwk jsonWebKey) (verificationKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		if jwk.Alg != "" && jwk.Alg != "RS256" {
+			return verificationKey{}, fmt.Errorf("unsupported algorithm %s", jwk.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil || len(n) == 0 {
+			return verificationKey{}, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return verificationKey{}, errors.New("invalid RSA exponent")
+		}
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return verificationKey{id: jwk.Kid, alg: "RS256", key: pub}, nil
+	case "oct":
+		if jwk.Alg != "" && jwk.Alg != "HS256" {
+			return verificationKey{}, fmt.Errorf("unsupported algorithm %s", jwk.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil || len(secret) < 32 {
+			return verificationKey{}, errors.New("HS256 keys must be at least 32 bytes")
+		}
+		return verificationKey{id: jwk.Kid, alg: "HS256", key: secret}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// lookup finds the key for a token's kid and alg headers. A token without a
+// kid is accepted when exactly one key uses its algorithm.
+func (s *KeySet) lookup(kid, alg string) (interface{}, error) {
+	var match *verificationKey
+	for i := range s.keys {
+		k := &s.keys[i]
+		if k.alg != alg || (kid != "" && k.id != kid) {
+			continue
+		}
+		if match != nil {
+			return nil, errors.New("token does not name its key (kid) and several keys match")
+		}
+		match = k
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("no %s key with kid %q", alg, kid)
+	}
+	return match.key, nil
+}
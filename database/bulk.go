@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxBatchSize bounds CreateBatch so a single statement stays well below
+// PostgreSQL's limit of 65535 bind parameters
+const MaxBatchSize = 1000
+
+// CreateBatch inserts tickets with a single multi-row INSERT and records their
+// history, all in one transaction. Either every ticket is created or none is.
+// Results are returned in the order of the input.
+func (r *TicketRepository) CreateBatch(ctx context.Context, tickets []*Ticket) (batch []*Ticket, err error) {
+	ctx, span := startSpan(ctx, "create_tickets", "INSERT")
+	defer func() { endSpan(span, len(batch), err) }()
+
+	if len(tickets) == 0 {
+		return nil, nil
+	}
+	if len(tickets) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch of %d tickets exceeds %d", ErrInvalidArgument, len(tickets), MaxBatchSize)
+	}
+
+	const columnsPerRow = 10
+	values := make([]string, 0, len(tickets))
+	args := make([]interface{}, 0, len(tickets)*columnsPerRow)
+
+	for i, ticket := range tickets {
+		// Convert tags to JSON
+		tagsJSON, err := json.Marshal(ticket.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags to JSON: %w", err)
+		}
+
+		placeholders := make([]string, columnsPerRow)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*columnsPerRow+j+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+
+		args = append(args,
+			ticket.ID,
+			ticket.Title,
+			ticket.Description,
+			ticket.Status,
+			ticket.Priority,
+			ticket.AssigneeID,
+			string(tagsJSON),
+			ticket.CreatedAt,
+			ticket.UpdatedAt,
+			ticket.ReporterID,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO tickets (id, title, description, status, priority, assignee_id, tags, created_at, updated_at, reporter_id)
+		VALUES %s
+		RETURNING %s`,
+		strings.Join(values, ", "), ticketColumns)
+
+	created := make(map[string]*Ticket, len(tickets))
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to create tickets: %w", classifyError(err))
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			ticket, err := scanTicket(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan ticket: %w", err)
+			}
+			created[ticket.ID] = ticket
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to create tickets: %w", classifyError(err))
+		}
+
+		events := make([]pendingEvent, 0, len(tickets))
+		for _, ticket := range tickets {
+			events = append(events, pendingEvent{
+				ticketID:  ticket.ID,
+				eventType: EventCreated,
+				changes:   diffTickets(nil, created[ticket.ID]),
+				ticket:    created[ticket.ID],
+			})
+		}
+		return recordEvents(ctx, tx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Ticket, len(tickets))
+	for i, ticket := range tickets {
+		result[i] = created[ticket.ID]
+	}
+	return result, nil
+}
+
+// GetByIDs retrieves the tickets with the given IDs in a single query, in the
+// order of ids. Tickets that do not exist or are in the trash are left out.
+func (r *TicketRepository) GetByIDs(ctx context.Context, ids []string) (tickets []*Ticket, err error) {
+	ctx, span := startSpan(ctx, "get_tickets", "SELECT")
+	defer func() { endSpan(span, len(tickets), err) }()
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var b queryBuilder
+	b.add(inList("id", len(ids)), toArgs(ids)...)
+	b.add("deleted_at IS NULL")
+	query := `SELECT ` + ticketColumns + ` FROM tickets ` + b.where()
+
+	rows, err := r.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	found := make(map[string]*Ticket, len(ids))
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ticket: %w", err)
+		}
+		found[ticket.ID] = ticket
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tickets: %w", classifyError(err))
+	}
+
+	tickets = make([]*Ticket, 0, len(found))
+	for _, id := range ids {
+		if ticket, ok := found[id]; ok {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// BatchUpdateFunc returns the changes to make to one ticket of a batch update.
+// It is called while the ticket is locked, so the changes may depend on its
+// current state.
+type BatchUpdateFunc func(current *Ticket) (map[string]interface{}, error)
+
+// BatchResult is the outcome for one ticket of a batch operation
+type BatchResult struct {
+	ID     string
+	Ticket *Ticket // set on success
+	Err    error
+}
+
+// UpdateBatch updates the tickets with the given IDs, or every ticket matching
+// filter when ids is empty, in a single transaction. Rows are locked in ID
+// order so concurrent batches cannot deadlock.
+//
+// When atomic is set the first failure rolls back the whole batch and is
+// returned. Otherwise every ticket is written under its own savepoint: a
+// failure only discards that ticket's changes and is reported in its result.
+// Results follow the order of ids, or ID order for a filter.
+func (r *TicketRepository) UpdateBatch(ctx context.Context, ids []string, filter ListFilter, atomic bool, fn BatchUpdateFunc) (results []BatchResult, err error) {
+	ctx, span := startSpan(ctx, "update_tickets", "UPDATE")
+	defer func() { endSpan(span, len(results), err) }()
+
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		results = nil
+
+		locked, order, err := lockBatch(ctx, tx, ids, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range order {
+			current, ok := locked[id]
+			if !ok {
+				if atomic {
+					return notFound(id)
+				}
+				results = append(results, BatchResult{ID: id, Err: notFound(id)})
+				continue
+			}
+
+			updated, err := updateBatchItem(ctx, tx, current, atomic, fn)
+			if err != nil && (atomic || ctx.Err() != nil || errors.Is(err, errSavepointLost)) {
+				return fmt.Errorf("ticket %s: %w", id, err)
+			}
+			results = append(results, BatchResult{ID: id, Ticket: updated, Err: err})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// errSavepointLost means a failed batch item could not be rolled back on its own
+var errSavepointLost = errors.New("failed to roll back batch item")
+
+// updateBatchItem applies fn to one locked ticket. Outside atomic mode the
+// write is wrapped in a savepoint so that its failure leaves tx usable.
+func updateBatchItem(ctx context.Context, tx *sql.Tx, current *Ticket, atomic bool, fn BatchUpdateFunc) (*Ticket, error) {
+	updates, err := fn(current)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic {
+		return updateLocked(ctx, tx, current, updates)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSavepointLost, classifyError(err))
+	}
+
+	updated, err := updateLocked(ctx, tx, current, updates)
+	if err != nil {
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); rbErr != nil {
+			return nil, fmt.Errorf("%w: %w", errSavepointLost, classifyError(rbErr))
+		}
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_item`); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSavepointLost, classifyError(err))
+	}
+	return updated, nil
+}
+
+// lockBatch locks the tickets selected by a batch update. It returns them by
+// ID together with the order in which they should be processed, which for
+// explicit ids includes IDs that do not exist.
+func lockBatch(ctx context.Context, tx *sql.Tx, ids []string, filter ListFilter) (map[string]*Ticket, []string, error) {
+	var b queryBuilder
+	if len(ids) > 0 {
+		if len(ids) > MaxBatchSize {
+			return nil, nil, fmt.Errorf("%w: batch of %d tickets exceeds %d", ErrInvalidArgument, len(ids), MaxBatchSize)
+		}
+		b.add(inList("id", len(ids)), toArgs(ids)...)
+	}
+	if err := b.addFilter(filter); err != nil {
+		return nil, nil, err
+	}
+
+	b.args = append(b.args, MaxBatchSize+1)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM tickets
+		%s
+		ORDER BY id
+		LIMIT $%d
+		FOR UPDATE`,
+		ticketColumns, b.where(), len(b.args))
+
+	rows, err := tx.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock tickets: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	locked := make(map[string]*Ticket)
+	var order []string
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan ticket: %w", err)
+		}
+		locked[ticket.ID] = ticket
+		order = append(order, ticket.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate tickets: %w", classifyError(err))
+	}
+
+	if len(order) > MaxBatchSize {
+		return nil, nil, fmt.Errorf("%w: filter matches more than %d tickets", ErrInvalidArgument, MaxBatchSize)
+	}
+	if len(ids) > 0 {
+		order = ids
+	}
+
+	return locked, order, nil
+}
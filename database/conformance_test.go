@@ -0,0 +1,714 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// storeFactory returns an empty TicketStore that lives as long as t
+type storeFactory func(t *testing.T) TicketStore
+
+// testTicketStore runs the conformance suite shared by every TicketStore
+// implementation. Each subtest gets a store of its own from newStore.
+func testTicketStore(t *testing.T, newStore storeFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store TicketStore)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"CreateColumnLimits", testCreateColumnLimits},
+		{"GetMissing", testGetMissing},
+		{"NullDescription", testNullDescription},
+		{"NilAndEmptyTags", testNilAndEmptyTags},
+		{"ListOrderingTies", testListOrderingTies},
+		{"ListDeletedAtTies", testListDeletedAtTies},
+		{"ListPriorityOrder", testListPriorityOrder},
+		{"ListFilters", testListFilters},
+		{"Update", testUpdate},
+		{"UpdateInvalid", testUpdateInvalid},
+		{"UpdateVersionConflict", testUpdateVersionConflict},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"Delete", testDelete},
+		{"EventTickets", testEventTickets},
+		{"SearchPaging", testSearchPaging},
+		{"SearchSnippetsEscapeHTML", testSearchSnippetsEscapeHTML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+// baseTime has nanoseconds, which every store rounds to microseconds
+var baseTime = time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+
+func newTestTicket(id string) *Ticket {
+	return &Ticket{
+		ID:          id,
+		Title:       "Ticket " + id,
+		Description: sql.NullString{String: "Description of " + id, Valid: true},
+		Status:      "OPEN",
+		Priority:    "MEDIUM",
+		Tags:        []string{"backend"},
+		CreatedAt:   baseTime,
+		UpdatedAt:   baseTime,
+		ReporterID:  "reporter-1",
+	}
+}
+
+func mustCreate(t *testing.T, store TicketStore, ticket *Ticket) *Ticket {
+	t.Helper()
+	created, err := store.Create(context.Background(), ticket)
+	if err != nil {
+		t.Fatalf("Create(%s) failed: %v", ticket.ID, err)
+	}
+	return created
+}
+
+func mustGet(t *testing.T, store TicketStore, id string) *Ticket {
+	t.Helper()
+	ticket, err := store.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%s) failed: %v", id, err)
+	}
+	return ticket
+}
+
+func mustList(t *testing.T, store TicketStore, q ListQuery, limit int, after *ListCursor) []*Ticket {
+	t.Helper()
+	tickets, err := store.List(context.Background(), q, limit, after)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	return tickets
+}
+
+func ticketIDs(tickets []*Ticket) []string {
+	ids := make([]string, len(tickets))
+	for i, ticket := range tickets {
+		ids[i] = ticket.ID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, got []*Ticket, want ...string) {
+	t.Helper()
+	if ids := ticketIDs(got); !reflect.DeepEqual(ids, want) && !(len(ids) == 0 && len(want) == 0) {
+		t.Errorf("got tickets %v, want %v", ids, want)
+	}
+}
+
+func assertErrorIs(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("got error %v, want %v", err, target)
+	}
+}
+
+// assertSameTicket compares tickets field by field, timestamps by instant
+func assertSameTicket(t *testing.T, got, want *Ticket) {
+	t.Helper()
+	if got.ID != want.ID || got.Title != want.Title || got.Description != want.Description ||
+		got.Status != want.Status || got.Priority != want.Priority || got.AssigneeID != want.AssigneeID ||
+		got.ReporterID != want.ReporterID || got.Version != want.Version || got.ResolutionNote != want.ResolutionNote {
+		t.Errorf("got ticket %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(got.Tags, want.Tags) {
+		t.Errorf("got tags %#v, want %#v", got.Tags, want.Tags)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("got timestamps %v/%v, want %v/%v", got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+	}
+	if got.DeletedAt.Valid != want.DeletedAt.Valid || !got.DeletedAt.Time.Equal(want.DeletedAt.Time) {
+		t.Errorf("got deleted at %v, want %v", got.DeletedAt, want.DeletedAt)
+	}
+}
+
+func testCreateAndGet(t *testing.T, store TicketStore) {
+	ticket := newTestTicket("t-1")
+	ticket.AssigneeID = sql.NullString{String: "assignee-1", Valid: true}
+	ticket.Tags = []string{"backend", "urgent"}
+
+	created := mustCreate(t, store, ticket)
+	if created.Version != 1 {
+		t.Errorf("created version = %d, want 1", created.Version)
+	}
+	if want := baseTime.Round(time.Microsecond); !created.CreatedAt.Equal(want) {
+		t.Errorf("created at = %v, want %v rounded to microseconds", created.CreatedAt, want)
+	}
+	if created.DeletedAt.Valid || created.ResolvedAt.Valid || created.ClosedAt.Valid {
+		t.Errorf("created ticket has workflow timestamps: %+v", created)
+	}
+
+	assertSameTicket(t, mustGet(t, store, "t-1"), created)
+
+	including, err := store.GetByIDIncludingDeleted(context.Background(), "t-1")
+	if err != nil {
+		t.Fatalf("GetByIDIncludingDeleted failed: %v", err)
+	}
+	assertSameTicket(t, including, created)
+}
+
+func testCreateDuplicate(t *testing.T, store TicketStore) {
+	original := mustCreate(t, store, newTestTicket("t-1"))
+
+	duplicate := newTestTicket("t-1")
+	duplicate.Title = "Duplicate"
+	_, err := store.Create(context.Background(), duplicate)
+	assertErrorIs(t, err, ErrAlreadyExists)
+
+	assertSameTicket(t, mustGet(t, store, "t-1"), original)
+}
+
+func testCreateColumnLimits(t *testing.T, store TicketStore) {
+	// Limits count characters, not bytes
+	ticket := newTestTicket("t-1")
+	ticket.Title = strings.Repeat("é", 500)
+	mustCreate(t, store, ticket)
+
+	tooLong := newTestTicket("t-2")
+	tooLong.Title = strings.Repeat("x", 501)
+	_, err := store.Create(context.Background(), tooLong)
+	assertErrorIs(t, err, ErrInvalidArgument)
+
+	_, err = store.GetByID(context.Background(), "t-2")
+	assertErrorIs(t, err, ErrNotFound)
+}
+
+func testGetMissing(t *testing.T, store TicketStore) {
+	_, err := store.GetByID(context.Background(), "missing")
+	assertErrorIs(t, err, ErrNotFound)
+
+	_, err = store.GetByIDIncludingDeleted(context.Background(), "missing")
+	assertErrorIs(t, err, ErrNotFound)
+}
+
+func testNullDescription(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+
+	null := newTestTicket("null")
+	null.Description = sql.NullString{}
+	mustCreate(t, store, null)
+
+	empty := newTestTicket("empty")
+	empty.Description = sql.NullString{String: "", Valid: true}
+	mustCreate(t, store, empty)
+
+	if got := mustGet(t, store, "null").Description; got.Valid {
+		t.Errorf("NULL description read back as %+v", got)
+	}
+	if got := mustGet(t, store, "empty").Description; !got.Valid || got.String != "" {
+		t.Errorf("empty description read back as %+v", got)
+	}
+
+	// A nil update clears the description, a string sets it
+	updated, err := store.Update(ctx, "empty", map[string]interface{}{"description": nil}, 0)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Description.Valid {
+		t.Errorf("cleared description = %+v, want NULL", updated.Description)
+	}
+
+	updated, err = store.Update(ctx, "null", map[string]interface{}{"description": "now set"}, 0)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if want := (sql.NullString{String: "now set", Valid: true}); updated.Description != want {
+		t.Errorf("set description = %+v, want %+v", updated.Description, want)
+	}
+	assertSameTicket(t, mustGet(t, store, "null"), updated)
+}
+
+func testNilAndEmptyTags(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+
+	nilTags := newTestTicket("nil")
+	nilTags.Tags = nil
+	mustCreate(t, store, nilTags)
+
+	emptyTags := newTestTicket("empty")
+	emptyTags.Tags = []string{}
+	mustCreate(t, store, emptyTags)
+
+	tagged := newTestTicket("tagged")
+	tagged.Tags = []string{"backend", "urgent"}
+	mustCreate(t, store, tagged)
+
+	// Tags read back exactly as they were created, nil or empty
+	want := map[string][]string{"nil": nil, "empty": {}, "tagged": {"backend", "urgent"}}
+	for id, tags := range want {
+		if got := mustGet(t, store, id).Tags; !reflect.DeepEqual(got, tags) {
+			t.Errorf("ticket %s read back with tags %#v, want %#v", id, got, tags)
+		}
+	}
+	for _, ticket := range mustList(t, store, ListQuery{}, 10, nil) {
+		if !reflect.DeepEqual(ticket.Tags, want[ticket.ID]) {
+			t.Errorf("ticket %s listed with tags %#v, want %#v", ticket.ID, ticket.Tags, want[ticket.ID])
+		}
+	}
+
+	// Untagged tickets are listed, but never match a tag filter
+	assertIDs(t, mustList(t, store, ListQuery{Ascending: true}, 10, nil), "empty", "nil", "tagged")
+	assertIDs(t, mustList(t, store, ListQuery{Filter: ListFilter{Tags: []string{"backend"}}}, 10, nil), "tagged")
+	assertIDs(t, mustList(t, store, ListQuery{Filter: ListFilter{Tags: []string{"backend", "urgent"}}}, 10, nil), "tagged")
+	assertIDs(t, mustList(t, store, ListQuery{Filter: ListFilter{Tags: []string{"backend", "missing"}}}, 10, nil))
+
+	// Updating tags to nil clears them, and stores an empty list
+	updated, err := store.Update(ctx, "tagged", map[string]interface{}{"tags": nil}, 0)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !reflect.DeepEqual(updated.Tags, []string{}) {
+		t.Errorf("cleared tags = %#v, want an empty list", updated.Tags)
+	}
+	if tags := mustGet(t, store, "tagged").Tags; !reflect.DeepEqual(tags, []string{}) {
+		t.Errorf("cleared tags read back as %#v, want an empty list", tags)
+	}
+	assertIDs(t, mustList(t, store, ListQuery{Filter: ListFilter{Tags: []string{"backend"}}}, 10, nil))
+
+	updated, err = store.Update(ctx, "nil", map[string]interface{}{"tags": []string{"frontend"}}, 0)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if want := []string{"frontend"}; !reflect.DeepEqual(updated.Tags, want) {
+		t.Errorf("set tags = %q, want %q", updated.Tags, want)
+	}
+	assertIDs(t, mustList(t, store, ListQuery{Filter: ListFilter{Tags: []string{"frontend"}}}, 10, nil), "nil")
+}
+
+func testListOrderingTies(t *testing.T, store TicketStore) {
+	// Every ticket has the same timestamps, so only the ID orders them
+	ids := []string{"t-3", "t-1", "t-5", "t-2", "t-4"}
+	for _, id := range ids {
+		mustCreate(t, store, newTestTicket(id))
+	}
+
+	for _, sortBy := range []SortField{SortByCreatedAt, SortByUpdatedAt, SortByPriority} {
+		for _, ascending := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/ascending=%t", sortBy, ascending), func(t *testing.T) {
+				q := ListQuery{SortBy: sortBy, Ascending: ascending}
+				want := []string{"t-1", "t-2", "t-3", "t-4", "t-5"}
+				if !ascending {
+					want = []string{"t-5", "t-4", "t-3", "t-2", "t-1"}
+				}
+
+				assertIDs(t, mustList(t, store, q, 10, nil), want...)
+				assertPagedIDs(t, store, q, want...)
+			})
+		}
+	}
+}
+
+func testListDeletedAtTies(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	for _, id := range []string{"t-3", "t-1", "t-5", "t-2", "t-4"} {
+		mustCreate(t, store, newTestTicket(id))
+	}
+
+	// Tickets deleted at the same moment are ordered by ID
+	deletedAt := baseTime.Add(time.Hour)
+	timeNow = func() time.Time { return deletedAt }
+	defer func() { timeNow = time.Now }()
+	for _, id := range []string{"t-4", "t-2", "t-5", "t-1", "t-3"} {
+		if err := store.Delete(ctx, id, 0); err != nil {
+			t.Fatalf("Delete(%s) failed: %v", id, err)
+		}
+	}
+
+	for _, ascending := range []bool{true, false} {
+		t.Run(fmt.Sprintf("ascending=%t", ascending), func(t *testing.T) {
+			q := ListQuery{Filter: ListFilter{Deleted: true}, SortBy: SortByDeletedAt, Ascending: ascending}
+			want := []string{"t-1", "t-2", "t-3", "t-4", "t-5"}
+			if !ascending {
+				want = []string{"t-5", "t-4", "t-3", "t-2", "t-1"}
+			}
+
+			assertIDs(t, mustList(t, store, q, 10, nil), want...)
+			assertPagedIDs(t, store, q, want...)
+		})
+	}
+}
+
+// assertPagedIDs lists q in pages of two, which must neither skip nor repeat
+// tickets within a tie
+func assertPagedIDs(t *testing.T, store TicketStore, q ListQuery, want ...string) {
+	t.Helper()
+	var paged []*Ticket
+	var after *ListCursor
+	for page := 0; page <= len(want); page++ {
+		tickets := mustList(t, store, q, 2, after)
+		paged = append(paged, tickets...)
+		if len(tickets) < 2 {
+			break
+		}
+		cursor := CursorFor(tickets[len(tickets)-1])
+		after = &cursor
+	}
+	assertIDs(t, paged, want...)
+}
+
+func testListPriorityOrder(t *testing.T, store TicketStore) {
+	// Priorities sort by severity rather than alphabetically
+	for id, priority := range map[string]string{
+		"a": "LOW", "b": "CRITICAL", "c": "MEDIUM", "d": "HIGH", "e": "CRITICAL", "f": "LOW",
+	} {
+		ticket := newTestTicket(id)
+		ticket.Priority = priority
+		mustCreate(t, store, ticket)
+	}
+
+	assertIDs(t, mustList(t, store, ListQuery{SortBy: SortByPriority}, 10, nil), "e", "b", "d", "c", "f", "a")
+	assertIDs(t, mustList(t, store, ListQuery{SortBy: SortByPriority, Ascending: true}, 10, nil), "a", "f", "c", "d", "b", "e")
+
+	after := ListCursor{Priority: "CRITICAL", ID: "b"}
+	assertIDs(t, mustList(t, store, ListQuery{SortBy: SortByPriority}, 2, &after), "d", "c")
+}
+
+func testListFilters(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+
+	for i, spec := range []struct {
+		id, status, priority, assignee string
+	}{
+		{"a", "OPEN", "HIGH", "alice"},
+		{"b", "IN_PROGRESS", "LOW", "bob"},
+		{"c", "OPEN", "LOW", ""},
+		{"d", "CLOSED", "HIGH", "alice"},
+	} {
+		ticket := newTestTicket(spec.id)
+		ticket.Status = spec.status
+		ticket.Priority = spec.priority
+		ticket.AssigneeID = sql.NullString{String: spec.assignee, Valid: spec.assignee != ""}
+		ticket.CreatedAt = baseTime.Add(time.Duration(i) * time.Hour)
+		mustCreate(t, store, ticket)
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"All", ListFilter{}, []string{"d", "c", "b", "a"}},
+		{"Statuses", ListFilter{Statuses: []string{"OPEN", "CLOSED"}}, []string{"d", "c", "a"}},
+		{"Priorities", ListFilter{Priorities: []string{"LOW"}}, []string{"c", "b"}},
+		{"Assignee", ListFilter{AssigneeID: "alice"}, []string{"d", "a"}},
+		{"Combined", ListFilter{Statuses: []string{"OPEN"}, Priorities: []string{"HIGH"}}, []string{"a"}},
+		{"CreatedRange", ListFilter{CreatedAfter: baseTime.Add(time.Hour), CreatedBefore: baseTime.Add(3 * time.Hour)}, []string{"c", "b"}},
+		{"NoMatch", ListFilter{ReporterID: "someone-else"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertIDs(t, mustList(t, store, ListQuery{Filter: tt.filter}, 10, nil), tt.want...)
+		})
+	}
+
+	// Deleted tickets only show up when asking for the trash
+	if err := store.Delete(ctx, "c", 0); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	assertIDs(t, mustList(t, store, ListQuery{}, 10, nil), "d", "b", "a")
+	assertIDs(t, mustList(t, store, ListQuery{Filter: ListFilter{Deleted: true}}, 10, nil), "c")
+}
+
+func testUpdate(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	created := mustCreate(t, store, newTestTicket("t-1"))
+
+	updated, err := store.Update(ctx, "t-1", map[string]interface{}{
+		"title":       "New title",
+		"status":      "IN_PROGRESS",
+		"assignee_id": "assignee-2",
+	}, created.Version)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if updated.Title != "New title" || updated.Status != "IN_PROGRESS" || updated.AssigneeID.String != "assignee-2" {
+		t.Errorf("update not applied: %+v", updated)
+	}
+	if updated.Version != created.Version+1 {
+		t.Errorf("version = %d, want %d", updated.Version, created.Version+1)
+	}
+	if !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Errorf("updated at %v did not advance from %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) || updated.Priority != created.Priority || updated.Description != created.Description {
+		t.Errorf("update changed other fields: %+v", updated)
+	}
+	assertSameTicket(t, mustGet(t, store, "t-1"), updated)
+
+	// An empty update changes nothing, not even the version
+	unchanged, err := store.Update(ctx, "t-1", map[string]interface{}{}, 0)
+	if err != nil {
+		t.Fatalf("empty Update failed: %v", err)
+	}
+	assertSameTicket(t, unchanged, updated)
+}
+
+func testUpdateInvalid(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	created := mustCreate(t, store, newTestTicket("t-1"))
+
+	for name, updates := range map[string]map[string]interface{}{
+		"UnknownField": {"reporter_id": "someone"},
+		"ClearTitle":   {"title": nil},
+		"TitleTooLong": {"title": strings.Repeat("x", 501)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Update(ctx, "t-1", updates, 0)
+			assertErrorIs(t, err, ErrInvalidArgument)
+		})
+	}
+	assertSameTicket(t, mustGet(t, store, "t-1"), created)
+
+	_, err := store.Update(ctx, "missing", map[string]interface{}{"title": "x"}, 0)
+	assertErrorIs(t, err, ErrNotFound)
+}
+
+func testUpdateVersionConflict(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	created := mustCreate(t, store, newTestTicket("t-1"))
+
+	updated, err := store.Update(ctx, "t-1", map[string]interface{}{"title": "First"}, created.Version)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	_, err = store.Update(ctx, "t-1", map[string]interface{}{"title": "Second"}, created.Version)
+	assertErrorIs(t, err, ErrVersionConflict)
+
+	assertSameTicket(t, mustGet(t, store, "t-1"), updated)
+}
+
+func testConcurrentUpdates(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	created := mustCreate(t, store, newTestTicket("t-1"))
+	const writers = 10
+
+	// Writers expecting the same version: exactly one wins
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = store.Update(ctx, "t-1", map[string]interface{}{"title": fmt.Sprintf("Writer %d", i)}, created.Version)
+		}(i)
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		switch {
+		case err == nil && winner < 0:
+			winner = i
+		case err == nil:
+			t.Errorf("writers %d and %d both updated version %d", winner, i, created.Version)
+		case !errors.Is(err, ErrVersionConflict):
+			t.Errorf("writer %d failed: %v", i, err)
+		}
+	}
+	if winner < 0 {
+		t.Fatal("no writer won")
+	}
+
+	ticket := mustGet(t, store, "t-1")
+	if want := fmt.Sprintf("Writer %d", winner); ticket.Title != want || ticket.Version != created.Version+1 {
+		t.Errorf("got %q at version %d, want %q at version %d", ticket.Title, ticket.Version, want, created.Version+1)
+	}
+
+	// Unconditional writers are serialized, none is lost
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = store.Update(ctx, "t-1", map[string]interface{}{"description": fmt.Sprintf("Writer %d", i)}, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("unconditional writer %d failed: %v", i, err)
+		}
+	}
+	if got, want := mustGet(t, store, "t-1").Version, created.Version+1+writers; got != want {
+		t.Errorf("version = %d, want %d", got, want)
+	}
+
+	history, err := store.History(ctx, "t-1", 100, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if got, want := len(history), 2+writers; got != want {
+		t.Errorf("got %d history events, want %d", got, want)
+	}
+}
+
+func testDelete(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	created := mustCreate(t, store, newTestTicket("t-1"))
+
+	err := store.Delete(ctx, "t-1", created.Version+1)
+	assertErrorIs(t, err, ErrVersionConflict)
+
+	if err := store.Delete(ctx, "t-1", created.Version); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	_, err = store.GetByID(ctx, "t-1")
+	assertErrorIs(t, err, ErrNotFound)
+
+	deleted, err := store.GetByIDIncludingDeleted(ctx, "t-1")
+	if err != nil {
+		t.Fatalf("GetByIDIncludingDeleted failed: %v", err)
+	}
+	if !deleted.DeletedAt.Valid {
+		t.Error("deleted ticket has no deleted at")
+	}
+
+	// Deleted tickets can be neither deleted again nor updated
+	assertErrorIs(t, store.Delete(ctx, "t-1", 0), ErrNotFound)
+	_, err = store.Update(ctx, "t-1", map[string]interface{}{"title": "x"}, 0)
+	assertErrorIs(t, err, ErrNotFound)
+	assertErrorIs(t, store.Delete(ctx, "missing", 0), ErrNotFound)
+
+	// The ID stays taken while the ticket is in the trash
+	_, err = store.Create(ctx, newTestTicket("t-1"))
+	assertErrorIs(t, err, ErrAlreadyExists)
+}
+
+// testEventTickets checks that every event keeps the ticket as it was right
+// after the event, whatever happened to the ticket later
+func testEventTickets(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	created := mustCreate(t, store, newTestTicket("t-1"))
+	updated, err := store.Update(ctx, "t-1", map[string]interface{}{"title": "Second", "tags": []string{"later"}}, 0)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete(ctx, "t-1", 0); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	history, err := store.History(ctx, "t-1", 100, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("got %d history events, want 3", len(history))
+	}
+	for i, want := range []*Ticket{created, updated} {
+		if history[i].Ticket == nil {
+			t.Fatalf("%s event has no ticket", history[i].Type)
+		}
+		assertSameTicket(t, history[i].Ticket, want)
+	}
+	if history[2].Ticket != nil {
+		t.Errorf("%s event has ticket %+v, want none", history[2].Type, history[2].Ticket)
+	}
+}
+
+// testSearchPaging pages through search results two at a time, with ties in
+// rank between tickets of the same text, and expects the single-page order
+func testSearchPaging(t *testing.T, store TicketStore) {
+	ctx := context.Background()
+	texts := map[string][2]string{
+		"s-1": {"Printer jammed", "The printer jams on every page"},
+		"s-2": {"Printer offline", ""},
+		"s-3": {"Printer offline", ""},
+		"s-4": {"Scanner broken", "Shares a cable with the printer"},
+		"s-5": {"Printer printer printer", "printer"},
+		"s-6": {"Network down", "Nothing to do with it"},
+	}
+	for id, text := range texts {
+		ticket := newTestTicket(id)
+		ticket.Title = text[0]
+		ticket.Description = sql.NullString{String: text[1], Valid: text[1] != ""}
+		mustCreate(t, store, ticket)
+	}
+
+	all, err := store.Search(ctx, "printer", ListFilter{}, 100, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("got %d results, want 5", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		prev, cur := all[i-1], all[i]
+		if prev.Rank < cur.Rank || (prev.Rank == cur.Rank && prev.Ticket.ID < cur.Ticket.ID) {
+			t.Errorf("results %s and %s are not ordered by rank, then ID", prev.Ticket.ID, cur.Ticket.ID)
+		}
+	}
+
+	var paged []*SearchResult
+	var after *ListCursor
+	for page := 0; page < len(all); page++ {
+		results, err := store.Search(ctx, "printer", ListFilter{}, 2, after)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) == 0 {
+			break
+		}
+		paged = append(paged, results...)
+		last := results[len(results)-1]
+		after = &ListCursor{Rank: last.Rank, ID: last.Ticket.ID}
+	}
+
+	var want, got []string
+	for _, r := range all {
+		want = append(want, r.Ticket.ID)
+	}
+	for _, r := range paged {
+		got = append(got, r.Ticket.ID)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("paged results %v, want %v", got, want)
+	}
+}
+
+func testSearchSnippetsEscapeHTML(t *testing.T, store TicketStore) {
+	ticket := newTestTicket("s-1")
+	ticket.Title = `Printer <script>alert("pwned")</script> & co`
+	// Delimiters in the text must not open markup of their own
+	ticket.Description = sql.NullString{String: "The \x02printer</b> \x03<img src=x onerror=alert(1)>", Valid: true}
+	mustCreate(t, store, ticket)
+
+	results, err := store.Search(context.Background(), "printer", ListFilter{}, 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	for name, snippet := range map[string]string{"title": results[0].TitleSnippet, "description": results[0].DescriptionSnippet} {
+		if !strings.Contains(snippet, "<b>") {
+			t.Errorf("%s snippet %q highlights nothing", name, snippet)
+		}
+		// Once the highlights are removed, no markup is left
+		plain := strings.NewReplacer("<b>", "", "</b>", "").Replace(snippet)
+		if strings.ContainsAny(plain, "<>\"\x02\x03") {
+			t.Errorf("%s snippet %q is not escaped", name, snippet)
+		}
+		if strings.Count(snippet, "<b>") != strings.Count(snippet, "</b>") {
+			t.Errorf("%s snippet %q has unbalanced highlights", name, snippet)
+		}
+	}
+	if want := "&lt;script&gt;"; !strings.Contains(results[0].TitleSnippet, want) {
+		t.Errorf("title snippet %q does not contain %q", results[0].TitleSnippet, want)
+	}
+}
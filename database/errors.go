@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Sentinel errors returned by the repository. Match them with errors.Is.
+var (
+	ErrNotFound           = errors.New("ticket not found")
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrAlreadyExists      = errors.New("ticket already exists")
+	ErrInvalidArgument    = errors.New("invalid ticket data")
+	ErrFailedPrecondition = errors.New("ticket precondition failed")
+	ErrVersionConflict    = errors.New("ticket version conflict")
+	ErrUnavailable        = errors.New("database unavailable")
+)
+
+// ConstraintError describes a write rejected by a database constraint.
+// It matches its Kind sentinel with errors.Is.
+type ConstraintError struct {
+	Kind       error
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("%v: %s: %v", e.Kind, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// notFound returns an ErrNotFound for the given ticket ID
+func notFound(id string) error {
+	return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// commentNotFound returns an ErrCommentNotFound for the given comment ID
+func commentNotFound(id string) error {
+	return fmt.Errorf("%w: %s", ErrCommentNotFound, id)
+}
+
+// versionConflict returns an ErrVersionConflict describing the mismatch
+func versionConflict(id string, current, expected int64) error {
+	return fmt.Errorf("%w: ticket %s is at version %d, expected %d", ErrVersionConflict, id, current, expected)
+}
+
+// classifyError maps driver errors onto the repository's sentinel errors
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		var kind error
+		switch {
+		case pqErr.Code == "23505": // unique_violation
+			kind = ErrAlreadyExists
+		case pqErr.Code == "23502", // not_null_violation
+			pqErr.Code == "23514", // check_violation
+			pqErr.Code == "22001", // string_data_right_truncation
+			pqErr.Code == "22P02": // invalid_text_representation
+			kind = ErrInvalidArgument
+		case pqErr.Code == "23503": // foreign_key_violation
+			kind = ErrFailedPrecondition
+		case pqErr.Code == "57014": // query_canceled, e.g. statement_timeout
+			return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+		case pqErr.Code.Class() == "08", // connection_exception
+			pqErr.Code.Class() == "53", // insufficient_resources
+			pqErr.Code.Class() == "57": // operator_intervention
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		default:
+			return err
+		}
+		return &ConstraintError{Kind: kind, Column: pqErr.Column, Constraint: pqErr.Constraint, Err: err}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return err
+}
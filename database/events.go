@@ -0,0 +1,414 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Event types recorded in ticket_events
+const (
+	EventCreated  = "CREATED"
+	EventUpdated  = "UPDATED"
+	EventDeleted  = "DELETED"
+	EventRestored = "RESTORED"
+	EventPurged   = "PURGED"
+)
+
+// SystemActor is recorded for changes made without an identified caller
+const SystemActor = "system"
+
+// FieldChange is the before and after value of one ticket field.
+// Nil means the field was unset (NULL) on that side of the change.
+type FieldChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+// TicketEvent is an entry in a ticket's change history
+type TicketEvent struct {
+	ID int64
+	// TxID is the PostgreSQL transaction that recorded the event, zero in
+	// stores that commit one transaction at a time
+	TxID      int64
+	TicketID  string
+	Type      string
+	Actor     string
+	Changes   []FieldChange
+	CreatedAt time.Time
+	// Ticket is the state of the ticket right after the event, nil once it
+	// was deleted or purged, and for events recorded before it was kept
+	Ticket *Ticket
+}
+
+// Position returns the place of the event in the order readers see events
+func (e *TicketEvent) Position() EventPosition {
+	return EventPosition{TxID: e.TxID, ID: e.ID}
+}
+
+// EventPosition orders ticket events so that readers never skip one.
+// PostgreSQL hands out event IDs when rows are inserted, not when they
+// commit, so an event can become visible after others with higher IDs.
+// Events are therefore ordered by the transaction that recorded them first,
+// and only the order up to EventHorizon is final. Stores that commit one
+// transaction at a time order events by ID alone.
+type EventPosition struct {
+	TxID int64
+	ID   int64
+}
+
+// Before reports whether p comes before q
+func (p EventPosition) Before(q EventPosition) bool {
+	if p.TxID != q.TxID {
+		return p.TxID < q.TxID
+	}
+	return p.ID < q.ID
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose writes are attributed to actor in the ticket history
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor, or SystemActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// History returns the events recorded for a ticket, oldest first, starting
+// after the event with ID afterID. History outlives the ticket itself.
+func (r *TicketRepository) History(ctx context.Context, ticketID string, limit int, afterID int64) (events []*TicketEvent, err error) {
+	ctx, span := startSpan(ctx, "get_ticket_history", "SELECT")
+	defer func() { endSpan(span, len(events), err) }()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM ticket_events
+		WHERE ticket_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, ticketID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket history: %w", classifyError(err))
+	}
+
+	return scanEvents(rows)
+}
+
+// EventsAfter returns events across all tickets positioned after the given
+// position, in order. Events positioned after EventHorizon may still be
+// preceded by events of transactions in flight.
+func (r *TicketRepository) EventsAfter(ctx context.Context, after EventPosition, limit int) (events []*TicketEvent, err error) {
+	ctx, span := startSpan(ctx, "list_ticket_events", "SELECT")
+	defer func() { endSpan(span, len(events), err) }()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM ticket_events
+		WHERE (txid, id) > ($1::text::xid8, $2)
+		ORDER BY txid, id
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, after.TxID, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ticket events: %w", classifyError(err))
+	}
+
+	return scanEvents(rows)
+}
+
+// EventHorizon returns the last position whose order is final: every event
+// at or before it is committed, and events committed from now on are
+// positioned after it. Transactions in flight hold it back until they end.
+func (r *TicketRepository) EventHorizon(ctx context.Context) (position EventPosition, err error) {
+	ctx, span := startSpan(ctx, "get_event_horizon", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
+
+	// Transactions older than the snapshot's xmin have all ended
+	var xmin int64
+	query := `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&xmin); err != nil {
+		return EventPosition{}, fmt.Errorf("failed to get event horizon: %w", classifyError(err))
+	}
+
+	return EventPosition{TxID: xmin - 1, ID: math.MaxInt64}, nil
+}
+
+// EventByID retrieves a single ticket event
+func (r *TicketRepository) EventByID(ctx context.Context, id int64) (event *TicketEvent, err error) {
+	ctx, span := startSpan(ctx, "get_ticket_event", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM ticket_events
+		WHERE id = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket event: %w", classifyError(err))
+	}
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: event %d", ErrNotFound, id)
+	}
+
+	return events[0], nil
+}
+
+// eventColumns lists the columns scanEvents reads, in order
+const eventColumns = `id, txid::text::bigint, ticket_id, event_type, actor, changes, ticket, created_at`
+
+// scanEvents reads and closes rows of ticket_events
+func scanEvents(rows *sql.Rows) ([]*TicketEvent, error) {
+	defer rows.Close()
+
+	var events []*TicketEvent
+	for rows.Next() {
+		var event TicketEvent
+		var changesJSON string
+		var ticketJSON sql.NullString
+		if err := rows.Scan(&event.ID, &event.TxID, &event.TicketID, &event.Type, &event.Actor, &changesJSON, &ticketJSON, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket event: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changesJSON), &event.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal changes from JSON: %w", err)
+		}
+		ticket, err := unmarshalEventTicket(ticketJSON)
+		if err != nil {
+			return nil, err
+		}
+		event.Ticket = ticket
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ticket events: %w", classifyError(err))
+	}
+
+	return events, nil
+}
+
+// pendingEvent is a history entry waiting to be written by recordEvents
+type pendingEvent struct {
+	ticketID  string
+	eventType string
+	changes   []FieldChange
+	ticket    *Ticket // state after the event, nil once deleted
+}
+
+// recordEvent appends an event to the ticket history within tx
+func recordEvent(ctx context.Context, tx *sql.Tx, ticketID, eventType string, changes []FieldChange, ticket *Ticket) error {
+	return recordEvents(ctx, tx, []pendingEvent{{ticketID: ticketID, eventType: eventType, changes: changes, ticket: ticket}})
+}
+
+// recordEvents appends events to the ticket history within tx using a single
+// multi-row INSERT. Every event is attributed to the actor of ctx.
+func recordEvents(ctx context.Context, tx *sql.Tx, events []pendingEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	actor := ActorFromContext(ctx)
+	values := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*5)
+
+	for _, event := range events {
+		changes := event.changes
+		if changes == nil {
+			changes = []FieldChange{}
+		}
+
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal changes to JSON: %w", err)
+		}
+		ticketJSON, err := marshalEventTicket(event.ticket)
+		if err != nil {
+			return err
+		}
+
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, event.ticketID, event.eventType, actor, string(changesJSON), ticketJSON)
+	}
+
+	query := `INSERT INTO ticket_events (ticket_id, event_type, actor, changes, ticket) VALUES ` + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record ticket event: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// eventTicket is the JSON form of the ticket stored with an event
+type eventTicket struct {
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Description    *string    `json:"description"`
+	Status         string     `json:"status"`
+	Priority       string     `json:"priority"`
+	AssigneeID     *string    `json:"assignee_id"`
+	Tags           []string   `json:"tags"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ReporterID     string     `json:"reporter_id"`
+	Version        int64      `json:"version"`
+	ResolutionNote *string    `json:"resolution_note"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+}
+
+// marshalEventTicket encodes the ticket stored with an event, NULL for none
+func marshalEventTicket(t *Ticket) (sql.NullString, error) {
+	if t == nil {
+		return sql.NullString{}, nil
+	}
+
+	b, err := json.Marshal(eventTicket{
+		ID:             t.ID,
+		Title:          t.Title,
+		Description:    nullStringField(t.Description),
+		Status:         t.Status,
+		Priority:       t.Priority,
+		AssigneeID:     nullStringField(t.AssigneeID),
+		Tags:           t.Tags,
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+		ReporterID:     t.ReporterID,
+		Version:        t.Version,
+		ResolutionNote: nullStringField(t.ResolutionNote),
+		ResolvedAt:     nullTimePointer(t.ResolvedAt),
+		ClosedAt:       nullTimePointer(t.ClosedAt),
+	})
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal ticket to JSON: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// unmarshalEventTicket decodes the ticket stored with an event
+func unmarshalEventTicket(s sql.NullString) (*Ticket, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	var t eventTicket
+	if err := json.Unmarshal([]byte(s.String), &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket from JSON: %w", err)
+	}
+	return &Ticket{
+		ID:             t.ID,
+		Title:          t.Title,
+		Description:    pointerNullString(t.Description),
+		Status:         t.Status,
+		Priority:       t.Priority,
+		AssigneeID:     pointerNullString(t.AssigneeID),
+		Tags:           t.Tags,
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+		ReporterID:     t.ReporterID,
+		Version:        t.Version,
+		ResolutionNote: pointerNullString(t.ResolutionNote),
+		ResolvedAt:     pointerNullTime(t.ResolvedAt),
+		ClosedAt:       pointerNullTime(t.ClosedAt),
+	}, nil
+}
+
+func nullTimePointer(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func pointerNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func pointerNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// diffTickets lists the user-visible fields that differ between two versions
+// of a ticket. A nil side is treated as a ticket with every field unset.
+func diffTickets(before, after *Ticket) []FieldChange {
+	if before == nil {
+		before = &Ticket{}
+	}
+	if after == nil {
+		after = &Ticket{}
+	}
+
+	var changes []FieldChange
+	add := func(field string, b, a *string) {
+		if (b == nil) != (a == nil) || (b != nil && *b != *a) {
+			changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+
+	add("title", stringField(before.Title), stringField(after.Title))
+	add("description", nullStringField(before.Description), nullStringField(after.Description))
+	add("status", stringField(before.Status), stringField(after.Status))
+	add("priority", stringField(before.Priority), stringField(after.Priority))
+	add("assignee_id", nullStringField(before.AssigneeID), nullStringField(after.AssigneeID))
+	add("tags", tagsField(before.Tags), tagsField(after.Tags))
+	add("reporter_id", stringField(before.ReporterID), stringField(after.ReporterID))
+	add("resolution_note", nullStringField(before.ResolutionNote), nullStringField(after.ResolutionNote))
+	add("resolved_at", nullTimeField(before.ResolvedAt), nullTimeField(after.ResolvedAt))
+	add("closed_at", nullTimeField(before.ClosedAt), nullTimeField(after.ClosedAt))
+
+	return changes
+}
+
+func stringField(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nullStringField(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTimeField(t sql.NullTime) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.UTC().Format(time.RFC3339Nano)
+	return &s
+}
+
+func tagsField(tags []string) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(tags)
+	s := string(b)
+	return &s
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+// eventFeed announces committed ticket events to listeners in the same
+// process. It stands in for LISTEN/NOTIFY in stores that have no
+// database server to notify through. The zero value is ready to use.
+type eventFeed struct {
+	mu        sync.Mutex
+	listeners map[*LocalEventListener]struct{}
+}
+
+// listen starts watching for events published from now on
+func (f *eventFeed) listen() *LocalEventListener {
+	l := &LocalEventListener{feed: f, wake: make(chan struct{}, 1)}
+
+	f.mu.Lock()
+	if f.listeners == nil {
+		f.listeners = make(map[*LocalEventListener]struct{})
+	}
+	f.listeners[l] = struct{}{}
+	f.mu.Unlock()
+
+	return l
+}
+
+// publish tells every listener that events have been committed
+func (f *eventFeed) publish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for l := range f.listeners {
+		l.notify()
+	}
+}
+
+// LocalEventListener announces the events committed by a MemoryStore or
+// SQLiteRepository in this process
+type LocalEventListener struct {
+	feed *eventFeed
+	wake chan struct{}
+}
+
+// notify wakes Run without blocking the writer. Announcements made before
+// Run gets to them are coalesced.
+func (l *LocalEventListener) notify() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls fn after events have been committed, until ctx is done
+func (l *LocalEventListener) Run(ctx context.Context, fn func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.wake:
+			fn()
+		}
+	}
+}
+
+// Close stops collecting events
+func (l *LocalEventListener) Close() error {
+	l.feed.mu.Lock()
+	delete(l.feed.listeners, l)
+	l.feed.mu.Unlock()
+	return nil
+}
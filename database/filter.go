@@ -0,0 +1,189 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SortField is a column List can order tickets by
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+	SortByPriority  SortField = "priority"
+	SortByDeletedAt SortField = "deleted_at"
+)
+
+// priorityRankSQL orders priorities by severity rather than alphabetically
+const priorityRankSQL = `CASE priority WHEN 'LOW' THEN 1 WHEN 'MEDIUM' THEN 2 WHEN 'HIGH' THEN 3 WHEN 'CRITICAL' THEN 4 ELSE 0 END`
+
+// PriorityRank returns the position of a priority in the ordering used by SortByPriority
+func PriorityRank(priority string) int {
+	switch priority {
+	case "LOW":
+		return 1
+	case "MEDIUM":
+		return 2
+	case "HIGH":
+		return 3
+	case "CRITICAL":
+		return 4
+	default:
+		return 0
+	}
+}
+
+// ListFilter restricts the tickets returned by List. Zero values match every ticket.
+type ListFilter struct {
+	Statuses      []string
+	Priorities    []string
+	AssigneeID    string
+	ReporterID    string
+	Tags          []string // tickets must carry all of these tags
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Deleted       bool // list tickets in the trash instead of live ones
+}
+
+// ListQuery describes which tickets List returns and in what order
+type ListQuery struct {
+	Filter    ListFilter
+	SortBy    SortField // defaults to SortByCreatedAt
+	Ascending bool
+}
+
+// ListCursor identifies the last ticket of a page. Only the field matching
+// the query's sort order is compared, with ID breaking ties. Rank is used
+// instead when paging through Search results.
+type ListCursor struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Priority  string
+	DeletedAt time.Time
+	Rank      float64
+	ID        string
+}
+
+// CursorFor returns the cursor positioned at the given ticket
+func CursorFor(ticket *Ticket) ListCursor {
+	return ListCursor{
+		CreatedAt: ticket.CreatedAt,
+		UpdatedAt: ticket.UpdatedAt,
+		Priority:  ticket.Priority,
+		DeletedAt: ticket.DeletedAt.Time,
+		ID:        ticket.ID,
+	}
+}
+
+// queryBuilder accumulates WHERE conditions and their positional arguments
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition, replacing each ? with the next positional parameter
+func (b *queryBuilder) add(condition string, args ...interface{}) {
+	for _, arg := range args {
+		b.args = append(b.args, arg)
+		condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(b.args)), 1)
+	}
+	b.conditions = append(b.conditions, condition)
+}
+
+// where returns the WHERE clause, or an empty string if there are no conditions
+func (b *queryBuilder) where() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// addFilter appends the conditions for a ListFilter
+func (b *queryBuilder) addFilter(filter ListFilter) error {
+	if filter.Deleted {
+		b.add("deleted_at IS NOT NULL")
+	} else {
+		b.add("deleted_at IS NULL")
+	}
+	if len(filter.Statuses) > 0 {
+		b.add(inList("status", len(filter.Statuses)), toArgs(filter.Statuses)...)
+	}
+	if len(filter.Priorities) > 0 {
+		b.add(inList("priority", len(filter.Priorities)), toArgs(filter.Priorities)...)
+	}
+	if filter.AssigneeID != "" {
+		b.add("assignee_id = ?", filter.AssigneeID)
+	}
+	if filter.ReporterID != "" {
+		b.add("reporter_id = ?", filter.ReporterID)
+	}
+	if len(filter.Tags) > 0 {
+		tagsJSON, err := json.Marshal(filter.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags to JSON: %w", err)
+		}
+		b.add("tags @> ?::jsonb", string(tagsJSON))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		b.add("created_at >= ?", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		b.add("created_at < ?", filter.CreatedBefore)
+	}
+	if !filter.UpdatedAfter.IsZero() {
+		b.add("updated_at >= ?", filter.UpdatedAfter)
+	}
+	if !filter.UpdatedBefore.IsZero() {
+		b.add("updated_at < ?", filter.UpdatedBefore)
+	}
+	return nil
+}
+
+// sortExpr returns the SQL expression for a sort field and the cursor value to compare it against
+func sortExpr(field SortField, cursor *ListCursor) (string, interface{}, error) {
+	switch field {
+	case "", SortByCreatedAt:
+		if cursor == nil {
+			return "created_at", nil, nil
+		}
+		return "created_at", cursor.CreatedAt, nil
+	case SortByUpdatedAt:
+		if cursor == nil {
+			return "updated_at", nil, nil
+		}
+		return "updated_at", cursor.UpdatedAt, nil
+	case SortByPriority:
+		if cursor == nil {
+			return priorityRankSQL, nil, nil
+		}
+		return priorityRankSQL, PriorityRank(cursor.Priority), nil
+	case SortByDeletedAt:
+		if cursor == nil {
+			return "deleted_at", nil, nil
+		}
+		return "deleted_at", cursor.DeletedAt, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported sort field: %s", field)
+	}
+}
+
+func inList(column string, n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", "))
+}
+
+func toArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
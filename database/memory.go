@@ -0,0 +1,768 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MemoryStore keeps tickets, their history and comments in memory. It
+// behaves like the PostgreSQL repositories, including ordering, errors and
+// tag handling, so the service can run without a database for local
+// development and tests. Nothing survives a restart.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	tickets   map[string]*Ticket
+	events    []*TicketEvent // ordered by ID
+	nextEvent int64
+	comments  map[string]*Comment
+	feed      eventFeed
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tickets:   make(map[string]*Ticket),
+		nextEvent: 1,
+		comments:  make(map[string]*Comment),
+	}
+}
+
+// dbTime rounds t to the microsecond precision of PostgreSQL timestamps
+func dbTime(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
+
+// cloneTicket copies a ticket so callers cannot modify stored state. Nil
+// tags stay nil and empty tags stay empty, as when read from JSONB.
+func cloneTicket(t *Ticket) *Ticket {
+	c := *t
+	if t.Tags != nil {
+		c.Tags = append([]string{}, t.Tags...)
+	}
+	return &c
+}
+
+// columnLimits are the VARCHAR lengths of the tickets table
+var columnLimits = []struct {
+	column string
+	value  func(*Ticket) string
+	limit  int
+}{
+	{"id", func(t *Ticket) string { return t.ID }, 255},
+	{"title", func(t *Ticket) string { return t.Title }, 500},
+	{"status", func(t *Ticket) string { return t.Status }, 50},
+	{"priority", func(t *Ticket) string { return t.Priority }, 50},
+	{"assignee_id", func(t *Ticket) string { return t.AssigneeID.String }, 255},
+	{"reporter_id", func(t *Ticket) string { return t.ReporterID }, 255},
+}
+
+// checkColumns rejects values PostgreSQL would refuse to store
+func checkColumns(t *Ticket) error {
+	for _, c := range columnLimits {
+		if utf8.RuneCountInString(c.value(t)) > c.limit {
+			return &ConstraintError{Kind: ErrInvalidArgument, Column: c.column, Err: fmt.Errorf("value too long for %s", c.column)}
+		}
+	}
+	return nil
+}
+
+// alreadyExists mirrors the unique violation on the tickets primary key
+func alreadyExists(id string) error {
+	return &ConstraintError{Kind: ErrAlreadyExists, Constraint: "tickets_pkey", Err: fmt.Errorf("ticket %s already exists", id)}
+}
+
+// newTicket prepares a ticket as Create would insert it
+func newTicket(ticket *Ticket) (*Ticket, error) {
+	t := &Ticket{
+		ID:          ticket.ID,
+		Title:       ticket.Title,
+		Description: ticket.Description,
+		Status:      ticket.Status,
+		Priority:    ticket.Priority,
+		AssigneeID:  ticket.AssigneeID,
+		Tags:        ticket.Tags,
+		CreatedAt:   dbTime(ticket.CreatedAt),
+		UpdatedAt:   dbTime(ticket.UpdatedAt),
+		ReporterID:  ticket.ReporterID,
+		Version:     1,
+	}
+	if err := checkColumns(t); err != nil {
+		return nil, err
+	}
+	return cloneTicket(t), nil
+}
+
+// recordEventsLocked appends events to the history and announces them to
+// listeners. The caller must hold the write lock.
+func (s *MemoryStore) recordEventsLocked(ctx context.Context, events []pendingEvent) {
+	actor := ActorFromContext(ctx)
+	now := dbTime(time.Now())
+
+	for _, event := range events {
+		e := cloneEvent(&TicketEvent{
+			ID:        s.nextEvent,
+			TicketID:  event.ticketID,
+			Type:      event.eventType,
+			Actor:     actor,
+			Changes:   event.changes,
+			CreatedAt: now,
+			Ticket:    event.ticket,
+		})
+		s.nextEvent++
+		s.events = append(s.events, e)
+	}
+	if len(events) > 0 {
+		s.feed.publish()
+	}
+}
+
+// Create creates a new ticket and records it in the ticket history
+func (s *MemoryStore) Create(ctx context.Context, ticket *Ticket) (*Ticket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t, err := newTicket(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tickets[t.ID]; ok {
+		return nil, alreadyExists(t.ID)
+	}
+	s.tickets[t.ID] = t
+	s.recordEventsLocked(ctx, []pendingEvent{{ticketID: t.ID, eventType: EventCreated, changes: diffTickets(nil, t), ticket: t}})
+
+	return cloneTicket(t), nil
+}
+
+// CreateBatch creates every ticket or none, and returns them in input order
+func (s *MemoryStore) CreateBatch(ctx context.Context, tickets []*Ticket) ([]*Ticket, error) {
+	if len(tickets) == 0 {
+		return nil, nil
+	}
+	if len(tickets) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch of %d tickets exceeds %d", ErrInvalidArgument, len(tickets), MaxBatchSize)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	created := make([]*Ticket, len(tickets))
+	for i, ticket := range tickets {
+		t, err := newTicket(ticket)
+		if err != nil {
+			return nil, err
+		}
+		created[i] = t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool, len(created))
+	for _, t := range created {
+		if _, ok := s.tickets[t.ID]; ok || seen[t.ID] {
+			return nil, alreadyExists(t.ID)
+		}
+		seen[t.ID] = true
+	}
+
+	events := make([]pendingEvent, len(created))
+	result := make([]*Ticket, len(created))
+	for i, t := range created {
+		s.tickets[t.ID] = t
+		events[i] = pendingEvent{ticketID: t.ID, eventType: EventCreated, changes: diffTickets(nil, t), ticket: t}
+		result[i] = cloneTicket(t)
+	}
+	s.recordEventsLocked(ctx, events)
+
+	return result, nil
+}
+
+// GetByID retrieves a ticket by ID. Tickets in the trash are not found.
+func (s *MemoryStore) GetByID(ctx context.Context, id string) (*Ticket, error) {
+	return s.getByID(ctx, id, false)
+}
+
+// GetByIDIncludingDeleted retrieves a ticket by ID even if it is in the trash
+func (s *MemoryStore) GetByIDIncludingDeleted(ctx context.Context, id string) (*Ticket, error) {
+	return s.getByID(ctx, id, true)
+}
+
+func (s *MemoryStore) getByID(ctx context.Context, id string, includeDeleted bool) (*Ticket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tickets[id]
+	if !ok || (!includeDeleted && t.DeletedAt.Valid) {
+		return nil, notFound(id)
+	}
+	return cloneTicket(t), nil
+}
+
+// GetByIDs retrieves the tickets with the given IDs in the order of ids,
+// leaving out those that do not exist or are in the trash
+func (s *MemoryStore) GetByIDs(ctx context.Context, ids []string) ([]*Ticket, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tickets := make([]*Ticket, 0, len(ids))
+	for _, id := range ids {
+		if t, ok := s.tickets[id]; ok && !t.DeletedAt.Valid {
+			tickets = append(tickets, cloneTicket(t))
+		}
+	}
+	return tickets, nil
+}
+
+// matchesFilter applies the conditions addFilter adds in SQL
+func matchesFilter(t *Ticket, filter ListFilter) bool {
+	if t.DeletedAt.Valid != filter.Deleted {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !containsString(filter.Statuses, t.Status) {
+		return false
+	}
+	if len(filter.Priorities) > 0 && !containsString(filter.Priorities, t.Priority) {
+		return false
+	}
+	if filter.AssigneeID != "" && (!t.AssigneeID.Valid || t.AssigneeID.String != filter.AssigneeID) {
+		return false
+	}
+	if filter.ReporterID != "" && t.ReporterID != filter.ReporterID {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		if !containsString(t.Tags, tag) {
+			return false
+		}
+	}
+	if !filter.CreatedAfter.IsZero() && t.CreatedAt.Before(filter.CreatedAfter) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && !t.CreatedAt.Before(filter.CreatedBefore) {
+		return false
+	}
+	if !filter.UpdatedAfter.IsZero() && t.UpdatedAt.Before(filter.UpdatedAfter) {
+		return false
+	}
+	if !filter.UpdatedBefore.IsZero() && !t.UpdatedAt.Before(filter.UpdatedBefore) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compareSortKey compares a ticket's value of a sort field with the cursor's,
+// returning -1, 0 or 1
+func compareSortKey(field SortField, t *Ticket, cursor ListCursor) int {
+	switch field {
+	case SortByUpdatedAt:
+		return t.UpdatedAt.Compare(cursor.UpdatedAt)
+	case SortByPriority:
+		a, b := PriorityRank(t.Priority), PriorityRank(cursor.Priority)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case SortByDeletedAt:
+		return t.DeletedAt.Time.Compare(cursor.DeletedAt)
+	default:
+		return t.CreatedAt.Compare(cursor.CreatedAt)
+	}
+}
+
+// compareTickets orders two tickets by a sort field, then ID, ascending
+func compareTickets(field SortField, a, b *Ticket) int {
+	if c := compareSortKey(field, a, CursorFor(b)); c != 0 {
+		return c
+	}
+	return compareStrings(a.ID, b.ID)
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// List retrieves tickets matching the query, starting strictly after the given cursor.
+// A nil cursor starts from the first ticket in the requested order.
+func (s *MemoryStore) List(ctx context.Context, q ListQuery, limit int, after *ListCursor) ([]*Ticket, error) {
+	if _, _, err := sortExpr(q.SortBy, after); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	direction := -1
+	if q.Ascending {
+		direction = 1
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tickets []*Ticket
+	for _, t := range s.tickets {
+		if !matchesFilter(t, q.Filter) {
+			continue
+		}
+		if after != nil {
+			c := compareSortKey(q.SortBy, t, *after)
+			if c == 0 {
+				c = compareStrings(t.ID, after.ID)
+			}
+			if c*direction <= 0 {
+				continue
+			}
+		}
+		tickets = append(tickets, t)
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return compareTickets(q.SortBy, tickets[i], tickets[j])*direction < 0
+	})
+	if len(tickets) > limit {
+		tickets = tickets[:limit]
+	}
+
+	for i, t := range tickets {
+		tickets[i] = cloneTicket(t)
+	}
+	return tickets, nil
+}
+
+// liveTicketLocked returns a ticket that is not in the trash, checking the
+// expected version. The caller must hold the lock.
+func (s *MemoryStore) liveTicketLocked(id string, expectedVersion int64) (*Ticket, error) {
+	t, ok := s.tickets[id]
+	if !ok || t.DeletedAt.Valid {
+		return nil, notFound(id)
+	}
+	if expectedVersion != 0 && t.Version != expectedVersion {
+		return nil, versionConflict(id, t.Version, expectedVersion)
+	}
+	return t, nil
+}
+
+// applyUpdates returns a copy of before with updates applied, as updateLocked
+// writes them. With no updates before itself is returned.
+func applyUpdates(before *Ticket, updates map[string]interface{}) (*Ticket, error) {
+	if len(updates) == 0 {
+		return before, nil
+	}
+
+	nullString := func(field string, value interface{}) (sql.NullString, error) {
+		if value == nil {
+			return sql.NullString{}, nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return sql.NullString{}, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("invalid value for %s", field)}
+		}
+		return sql.NullString{String: s, Valid: true}, nil
+	}
+
+	after := cloneTicket(before)
+	for field, value := range updates {
+		switch field {
+		case "title", "status", "priority":
+			if value == nil {
+				return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("%s cannot be cleared", field)}
+			}
+			s, err := nullString(field, value)
+			if err != nil {
+				return nil, err
+			}
+			switch field {
+			case "title":
+				after.Title = s.String
+			case "status":
+				after.Status = s.String
+			case "priority":
+				after.Priority = s.String
+			}
+		case "description", "assignee_id", "resolution_note":
+			s, err := nullString(field, value)
+			if err != nil {
+				return nil, err
+			}
+			switch field {
+			case "description":
+				after.Description = s
+			case "assignee_id":
+				after.AssigneeID = s
+			case "resolution_note":
+				after.ResolutionNote = s
+			}
+		case "resolved_at", "closed_at":
+			var t sql.NullTime
+			if value != nil {
+				v, ok := value.(time.Time)
+				if !ok {
+					return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("invalid value for %s", field)}
+				}
+				t = sql.NullTime{Time: dbTime(v), Valid: true}
+			}
+			if field == "resolved_at" {
+				after.ResolvedAt = t
+			} else {
+				after.ClosedAt = t
+			}
+		case "tags":
+			tags, _ := value.([]string)
+			after.Tags = append([]string{}, tags...)
+		default:
+			return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("unknown field %s", field)}
+		}
+	}
+
+	if err := checkColumns(after); err != nil {
+		return nil, err
+	}
+	after.Version++
+	after.UpdatedAt = dbTime(timeNow())
+
+	return after, nil
+}
+
+// updateLocked applies updates to a stored ticket and records the change.
+// The caller must hold the write lock.
+func (s *MemoryStore) updateLocked(ctx context.Context, before *Ticket, updates map[string]interface{}) (*Ticket, error) {
+	after, err := applyUpdates(before, updates)
+	if err != nil {
+		return nil, err
+	}
+	if after == before {
+		return cloneTicket(before), nil
+	}
+
+	s.tickets[after.ID] = after
+	s.recordEventsLocked(ctx, []pendingEvent{{ticketID: after.ID, eventType: EventUpdated, changes: diffTickets(before, after), ticket: after}})
+
+	return cloneTicket(after), nil
+}
+
+// Update updates an existing ticket. Only the fields present in updates are
+// changed; a nil value clears a nullable field and clears tags.
+func (s *MemoryStore) Update(ctx context.Context, id string, updates map[string]interface{}, expectedVersion int64) (*Ticket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.liveTicketLocked(id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateLocked(ctx, before, updates)
+}
+
+// UpdateBatch updates the tickets with the given IDs, or every ticket
+// matching filter when ids is empty, with the same atomic and best-effort
+// semantics as TicketRepository.UpdateBatch
+func (s *MemoryStore) UpdateBatch(ctx context.Context, ids []string, filter ListFilter, atomic bool, fn BatchUpdateFunc) ([]BatchResult, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch of %d tickets exceeds %d", ErrInvalidArgument, len(ids), MaxBatchSize)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	locked := make(map[string]*Ticket)
+	var order []string
+	for _, t := range s.tickets {
+		if len(ids) > 0 && !containsString(ids, t.ID) {
+			continue
+		}
+		if matchesFilter(t, filter) {
+			locked[t.ID] = t
+			order = append(order, t.ID)
+		}
+	}
+	if len(order) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: filter matches more than %d tickets", ErrInvalidArgument, MaxBatchSize)
+	}
+	sort.Strings(order)
+	if len(ids) > 0 {
+		order = ids
+	}
+
+	// Changes are staged so an atomic batch can be discarded as a whole
+	staged := make(map[string]*Ticket)
+	var events []pendingEvent
+	var results []BatchResult
+	for _, id := range order {
+		current, ok := staged[id]
+		if !ok {
+			current, ok = locked[id]
+		}
+		if !ok {
+			if atomic {
+				return nil, notFound(id)
+			}
+			results = append(results, BatchResult{ID: id, Err: notFound(id)})
+			continue
+		}
+
+		updates, err := fn(cloneTicket(current))
+		var updated *Ticket
+		if err == nil {
+			updated, err = applyUpdates(current, updates)
+		}
+		if err != nil {
+			if atomic {
+				return nil, fmt.Errorf("ticket %s: %w", id, err)
+			}
+			results = append(results, BatchResult{ID: id, Err: err})
+			continue
+		}
+
+		if updated != current {
+			staged[id] = updated
+			events = append(events, pendingEvent{ticketID: id, eventType: EventUpdated, changes: diffTickets(current, updated), ticket: updated})
+		}
+		results = append(results, BatchResult{ID: id, Ticket: cloneTicket(updated)})
+	}
+
+	for id, t := range staged {
+		s.tickets[id] = t
+	}
+	s.recordEventsLocked(ctx, events)
+
+	return results, nil
+}
+
+// Delete moves a ticket to the trash and records its final state in the
+// ticket history
+func (s *MemoryStore) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.liveTicketLocked(id, expectedVersion)
+	if err != nil {
+		return err
+	}
+
+	deleted := cloneTicket(before)
+	deleted.DeletedAt = sql.NullTime{Time: dbTime(timeNow()), Valid: true}
+	deleted.Version++
+	s.tickets[id] = deleted
+	s.recordEventsLocked(ctx, []pendingEvent{{ticketID: id, eventType: EventDeleted, changes: diffTickets(before, nil)}})
+
+	return nil
+}
+
+// Restore takes a ticket out of the trash and records it in the ticket history
+func (s *MemoryStore) Restore(ctx context.Context, id string, expectedVersion int64) (*Ticket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.tickets[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	if !before.DeletedAt.Valid {
+		return nil, &ConstraintError{Kind: ErrFailedPrecondition, Constraint: "NOT_DELETED", Err: fmt.Errorf("ticket %s is not deleted", id)}
+	}
+	if expectedVersion != 0 && before.Version != expectedVersion {
+		return nil, versionConflict(id, before.Version, expectedVersion)
+	}
+
+	restored := cloneTicket(before)
+	restored.DeletedAt = sql.NullTime{}
+	restored.Version++
+	s.tickets[id] = restored
+	s.recordEventsLocked(ctx, []pendingEvent{{ticketID: id, eventType: EventRestored, changes: diffTickets(nil, restored), ticket: restored}})
+
+	return cloneTicket(restored), nil
+}
+
+// PurgeDeleted permanently removes up to limit tickets that were moved to the
+// trash before olderThan, oldest first, together with their comments
+func (s *MemoryStore) PurgeDeleted(ctx context.Context, olderThan time.Time, limit int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*Ticket
+	for _, t := range s.tickets {
+		if t.DeletedAt.Valid && t.DeletedAt.Time.Before(olderThan) {
+			expired = append(expired, t)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return compareTickets(SortByDeletedAt, expired[i], expired[j]) < 0
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	events := make([]pendingEvent, len(expired))
+	for i, t := range expired {
+		delete(s.tickets, t.ID)
+		events[i] = pendingEvent{ticketID: t.ID, eventType: EventPurged}
+	}
+	for id, c := range s.comments {
+		if _, ok := s.tickets[c.TicketID]; !ok {
+			delete(s.comments, id)
+		}
+	}
+	s.recordEventsLocked(ctx, events)
+
+	return len(expired), nil
+}
+
+// cloneEvent copies an event so callers cannot modify the stored history
+func cloneEvent(e *TicketEvent) *TicketEvent {
+	c := *e
+	c.Changes = append([]FieldChange{}, e.Changes...)
+	if e.Ticket != nil {
+		c.Ticket = cloneTicket(e.Ticket)
+	}
+	return &c
+}
+
+// eventsAfter returns up to limit events with IDs greater than afterID that
+// match keep, oldest first
+func (s *MemoryStore) eventsAfter(ctx context.Context, afterID int64, limit int, keep func(*TicketEvent) bool) ([]*TicketEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.events), func(i int) bool { return s.events[i].ID > afterID })
+
+	var events []*TicketEvent
+	for _, e := range s.events[start:] {
+		if len(events) >= limit {
+			break
+		}
+		if keep(e) {
+			events = append(events, cloneEvent(e))
+		}
+	}
+	return events, nil
+}
+
+// History returns the events recorded for a ticket, oldest first, starting
+// after the event with ID afterID. History outlives the ticket itself.
+func (s *MemoryStore) History(ctx context.Context, ticketID string, limit int, afterID int64) ([]*TicketEvent, error) {
+	return s.eventsAfter(ctx, afterID, limit, func(e *TicketEvent) bool { return e.TicketID == ticketID })
+}
+
+// EventsAfter returns events across all tickets positioned after the given
+// position, oldest first. Events are committed one at a time, so their IDs
+// alone give the order.
+func (s *MemoryStore) EventsAfter(ctx context.Context, after EventPosition, limit int) ([]*TicketEvent, error) {
+	return s.eventsAfter(ctx, after.ID, limit, func(*TicketEvent) bool { return true })
+}
+
+// EventHorizon returns the position of the last event, since every event is
+// final as soon as it is recorded
+func (s *MemoryStore) EventHorizon(ctx context.Context) (EventPosition, error) {
+	if err := ctx.Err(); err != nil {
+		return EventPosition{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return EventPosition{ID: s.nextEvent - 1}, nil
+}
+
+// EventByID retrieves a single ticket event
+func (s *MemoryStore) EventByID(ctx context.Context, id int64) (*TicketEvent, error) {
+	events, err := s.eventsAfter(ctx, id-1, 1, func(*TicketEvent) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 || events[0].ID != id {
+		return nil, fmt.Errorf("%w: event %d", ErrNotFound, id)
+	}
+	return events[0], nil
+}
+
+// CountByStatusAndPriority counts the tickets that are not in the trash
+func (s *MemoryStore) CountByStatusAndPriority(ctx context.Context) ([]TicketCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type key struct{ status, priority string }
+	counts := make(map[key]int64)
+	for _, t := range s.tickets {
+		if !t.DeletedAt.Valid {
+			counts[key{t.Status, t.Priority}]++
+		}
+	}
+
+	var result []TicketCount
+	for k, n := range counts {
+		result = append(result, TicketCount{Status: k.status, Priority: k.priority, Count: n})
+	}
+	return result, nil
+}
+
+// NewEventListener starts watching for events committed from now on
+func (s *MemoryStore) NewEventListener() *LocalEventListener {
+	return s.feed.listen()
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// memoryComments is the CommentStore of a MemoryStore. Comments are removed
+// together with their ticket when it is purged.
+type memoryComments struct {
+	s *MemoryStore
+}
+
+// Comments returns the comment store sharing this store's tickets
+func (s *MemoryStore) Comments() CommentStore {
+	return memoryComments{s: s}
+}
+
+func cloneComment(c *Comment) *Comment {
+	clone := *c
+	return &clone
+}
+
+// liveTicketExistsLocked reports whether a ticket exists and is not in the
+// trash. The caller must hold the lock.
+func (s *MemoryStore) liveTicketExistsLocked(id string) bool {
+	t, ok := s.tickets[id]
+	return ok && !t.DeletedAt.Valid
+}
+
+// Create adds a comment to an existing ticket that is not in the trash
+func (m memoryComments) Create(ctx context.Context, comment *Comment) (*Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for column, value := range map[string]string{"id": comment.ID, "author_id": comment.AuthorID} {
+		if utf8.RuneCountInString(value) > 255 {
+			return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: column, Err: fmt.Errorf("value too long for %s", column)}
+		}
+	}
+
+	s := m.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.liveTicketExistsLocked(comment.TicketID) {
+		return nil, notFound(comment.TicketID)
+	}
+	if _, ok := s.comments[comment.ID]; ok {
+		return nil, &ConstraintError{Kind: ErrAlreadyExists, Constraint: "comments_pkey", Err: fmt.Errorf("comment %s already exists", comment.ID)}
+	}
+
+	created := &Comment{
+		ID:        comment.ID,
+		TicketID:  comment.TicketID,
+		AuthorID:  comment.AuthorID,
+		Body:      comment.Body,
+		CreatedAt: dbTime(comment.CreatedAt),
+	}
+	s.comments[created.ID] = created
+
+	return cloneComment(created), nil
+}
+
+// GetByID retrieves a comment by ID
+func (m memoryComments) GetByID(ctx context.Context, id string) (*Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := m.s
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.comments[id]
+	if !ok {
+		return nil, commentNotFound(id)
+	}
+	return cloneComment(c), nil
+}
+
+// List retrieves the comments on a ticket oldest first, starting strictly
+// after the CreatedAt and ID of the given cursor
+func (m memoryComments) List(ctx context.Context, ticketID string, limit int, after *ListCursor) ([]*Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := m.s
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.liveTicketExistsLocked(ticketID) {
+		return nil, notFound(ticketID)
+	}
+
+	compare := func(c *Comment, createdAt time.Time, id string) int {
+		if n := c.CreatedAt.Compare(createdAt); n != 0 {
+			return n
+		}
+		return compareStrings(c.ID, id)
+	}
+
+	var comments []*Comment
+	for _, c := range s.comments {
+		if c.TicketID != ticketID {
+			continue
+		}
+		if after != nil && compare(c, after.CreatedAt, after.ID) <= 0 {
+			continue
+		}
+		comments = append(comments, c)
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		return compare(comments[i], comments[j].CreatedAt, comments[j].ID) < 0
+	})
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+
+	for i, c := range comments {
+		comments[i] = cloneComment(c)
+	}
+	return comments, nil
+}
+
+// Update replaces the body of a comment and stamps its edit time
+func (m memoryComments) Update(ctx context.Context, id, body string) (*Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := m.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.comments[id]
+	if !ok {
+		return nil, commentNotFound(id)
+	}
+
+	updated := cloneComment(c)
+	updated.Body = body
+	updated.EditedAt = sql.NullTime{Time: dbTime(time.Now()), Valid: true}
+	s.comments[id] = updated
+
+	return cloneComment(updated), nil
+}
+
+// Delete deletes a comment by ID
+func (m memoryComments) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := m.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.comments[id]; !ok {
+		return commentNotFound(id)
+	}
+	delete(s.comments, id)
+
+	return nil
+}
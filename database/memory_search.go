@@ -0,0 +1,325 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Snippet sizes matching headlineOptions
+const (
+	headlineMaxWords = 35
+	headlineMinWords = 15
+)
+
+// Weights of title and description matches, as ts_rank weighs A and B labels
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// searchStopWords are left out of documents and queries, like the english
+// text search configuration does
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// stem reduces a lowercase word to a crude stem, so that "crash", "crashes"
+// and "crashing" match each other as they do in PostgreSQL
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "ly", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 && !strings.HasSuffix(word, "ss") {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// searchWord is a word of a document, located by byte offsets. Stem is empty
+// for stop words.
+type searchWord struct {
+	start, end int
+	stem       string
+}
+
+// splitWords splits text into words of letters and digits
+func splitWords(text string) []searchWord {
+	var words []searchWord
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(text[start:end])
+		s := ""
+		if !searchStopWords[word] {
+			s = stem(word)
+		}
+		words = append(words, searchWord{start: start, end: end, stem: s})
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+		} else {
+			flush(i)
+		}
+	}
+	flush(len(text))
+
+	return words
+}
+
+// stems returns the stems of the words of text, without stop words
+func stems(text string) []string {
+	var result []string
+	for _, w := range splitWords(text) {
+		if w.stem != "" {
+			result = append(result, w.stem)
+		}
+	}
+	return result
+}
+
+// searchClause is a word, or a phrase of several, that a ticket must contain,
+// or must not contain when negated
+type searchClause struct {
+	stems   []string
+	words   []string // lowercase as typed, keeping stop words within phrases, for matching by word rather than by stem
+	negated bool
+}
+
+// searchQuery is a disjunction of conjunctions of clauses
+type searchQuery [][]searchClause
+
+// parseSearch reads text with the web search syntax of websearch_to_tsquery:
+// quoted phrases, "or" between alternatives and a leading "-" to exclude a word
+func parseSearch(text string) searchQuery {
+	var query searchQuery
+	var group []searchClause
+	add := func(raw string, negated bool) {
+		s := stems(raw)
+		if len(s) == 0 {
+			return
+		}
+		var words []string
+		for _, w := range splitWords(raw) {
+			words = append(words, strings.ToLower(raw[w.start:w.end]))
+		}
+		group = append(group, searchClause{stems: s, words: words, negated: negated})
+	}
+
+	for rest := strings.TrimSpace(text); rest != ""; rest = strings.TrimSpace(rest) {
+		negated := false
+		if strings.HasPrefix(rest, "-") {
+			negated = true
+			rest = rest[1:]
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				add(rest[1:], negated)
+				break
+			}
+			add(rest[1:end+1], negated)
+			rest = rest[end+2:]
+			continue
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+
+		if !negated && strings.EqualFold(word, "or") {
+			if len(group) > 0 {
+				query = append(query, group)
+				group = nil
+			}
+			continue
+		}
+		add(word, negated)
+	}
+	if len(group) > 0 {
+		query = append(query, group)
+	}
+
+	return query
+}
+
+// count returns how often a clause occurs in a sequence of stems
+func (c searchClause) count(doc []string) int {
+	n := 0
+	for i := 0; i+len(c.stems) <= len(doc); i++ {
+		match := true
+		for j, s := range c.stems {
+			if doc[i+j] != s {
+				match = false
+				break
+			}
+		}
+		if match {
+			n++
+		}
+	}
+	return n
+}
+
+// rank scores a ticket's title and description against the query. It
+// reports false when the ticket does not match.
+func (q searchQuery) rank(title, description []string) (float32, bool) {
+	matched := false
+	score := 0.0
+	for _, group := range q {
+		groupMatched := true
+		groupScore := 0.0
+		for _, clause := range group {
+			inTitle, inDescription := clause.count(title), clause.count(description)
+			if clause.negated {
+				if inTitle+inDescription > 0 {
+					groupMatched = false
+				}
+				continue
+			}
+			if inTitle+inDescription == 0 {
+				groupMatched = false
+			}
+			groupScore += titleWeight*float64(inTitle) + descriptionWeight*float64(inDescription)
+		}
+		if groupMatched {
+			matched = true
+			score += groupScore
+		}
+	}
+	if !matched {
+		return 0, false
+	}
+
+	// Bounded below 1 like ts_rank, growing with every additional occurrence
+	return float32(score / (score + 10)), true
+}
+
+// highlighted returns the stems the query looks for, which headline marks
+func (q searchQuery) highlighted() map[string]bool {
+	stems := make(map[string]bool)
+	for _, group := range q {
+		for _, clause := range group {
+			if clause.negated {
+				continue
+			}
+			for _, s := range clause.stems {
+				stems[s] = true
+			}
+		}
+	}
+	return stems
+}
+
+// headline returns an excerpt of text with the query's words marked as
+// ts_headline marks them with headlineOptions
+func (q searchQuery) headline(text string) string {
+	words := splitWords(text)
+	if len(words) == 0 {
+		return text
+	}
+	marked := q.highlighted()
+
+	first, last := 0, len(words)
+	if len(words) > headlineMaxWords {
+		hit := -1
+		for i, w := range words {
+			if marked[w.stem] {
+				hit = i
+				break
+			}
+		}
+		if hit < 0 {
+			last = headlineMinWords
+		} else {
+			first = max(0, min(hit-headlineMinWords/3, len(words)-headlineMaxWords))
+			last = first + headlineMaxWords
+		}
+	}
+
+	var b strings.Builder
+	prev := words[first].start
+	if first == 0 && last == len(words) {
+		prev = 0
+	}
+	for _, w := range words[first:last] {
+		b.WriteString(text[prev:w.start])
+		if marked[w.stem] {
+			b.WriteString(snippetStart + text[w.start:w.end] + snippetStop)
+		} else {
+			b.WriteString(text[w.start:w.end])
+		}
+		prev = w.end
+	}
+	if first == 0 && last == len(words) {
+		b.WriteString(text[prev:])
+	}
+
+	return b.String()
+}
+
+// Search ranks tickets by relevance of their title and description to free
+// text, approximating PostgreSQL's English full-text search. Results are
+// ordered by rank, then ID, and paged with the Rank and ID of the cursor.
+func (s *MemoryStore) Search(ctx context.Context, text string, filter ListFilter, limit int, after *ListCursor) ([]*SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := parseSearch(text)
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []*SearchResult
+	for _, t := range s.tickets {
+		if !matchesFilter(t, filter) {
+			continue
+		}
+		score, ok := query.rank(stems(t.Title), stems(t.Description.String))
+		if !ok {
+			continue
+		}
+		rank := float64(score)
+		if after != nil && (rank > after.Rank || (rank == after.Rank && t.ID >= after.ID)) {
+			continue
+		}
+		results = append(results, &SearchResult{Ticket: t, Rank: rank})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Ticket.ID > results[j].Ticket.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	for _, r := range results {
+		r.TitleSnippet = renderSnippet(query.headline(r.Ticket.Title))
+		r.DescriptionSnippet = renderSnippet(query.headline(r.Ticket.Description.String))
+		r.Ticket = cloneTicket(r.Ticket)
+	}
+	return results, nil
+}
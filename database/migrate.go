@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationFiles holds the schema migrations of PostgreSQL in migrations/
+// and of SQLite in sqlite_migrations/, named NNNN_description.up.sql and
+// NNNN_description.down.sql
+//
+//go:embed migrations/*.sql sqlite_migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey identifies the advisory lock held while migrating, so that
+// replicas starting together apply each migration exactly once
+const migrationLockKey = 7_428_135_901
+
+// migrationDialect is what differs between the databases a Migrator runs on
+type migrationDialect struct {
+	dir         string // of the migration files
+	createTable string // creates schema_migrations if needed
+	lock        string // takes migrationLockKey, if the database needs a lock
+	unlock      string
+}
+
+var postgresMigrations = migrationDialect{
+	dir: "migrations",
+	createTable: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`,
+	lock:   `SELECT pg_advisory_lock($1)`,
+	unlock: `SELECT pg_advisory_unlock($1)`,
+}
+
+// SQLite needs no lock: each migration takes the write lock when its
+// transaction begins, and a database file belongs to a single server
+var sqliteMigrations = migrationDialect{
+	dir: "sqlite_migrations",
+	createTable: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+}
+
+// migrationFileName matches the name of a migration file
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt sql.NullTime
+}
+
+// Migrator applies the embedded schema migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []Migration // ordered by version
+}
+
+// NewMigrator creates a migrator for a PostgreSQL db. It fails if the
+// embedded migration files are malformed, which would be a bug in the build.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	return newMigrator(db, postgresMigrations)
+}
+
+// NewSQLiteMigrator creates a migrator for a SQLite db
+func NewSQLiteMigrator(db *sql.DB) (*Migrator, error) {
+	return newMigrator(db, sqliteMigrations)
+}
+
+func newMigrator(db *sql.DB, dialect migrationDialect) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, dialect.dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// loadMigrations reads and pairs the up and down files in dir of fsys
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, dir+"/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, p := range paths {
+		m := migrationFileName.FindStringSubmatch(path.Base(p))
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", p)
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", p)
+		}
+
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.up = string(body)
+		} else {
+			migration.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock,
+// creating the schema_migrations table first if needed
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", classifyError(err))
+	}
+	defer conn.Close()
+
+	// Session-level lock: it is held by this connection until released
+	if m.dialect.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.lock, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", classifyError(err))
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.unlock, migrationLockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", classifyError(err))
+	}
+
+	return fn(conn)
+}
+
+// applied returns when each applied migration was applied, by version
+func applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", classifyError(err))
+	}
+
+	return versions, nil
+}
+
+// run executes one migration script and records the result in a single
+// transaction, so a failed migration leaves no trace
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err))
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return classifyError(err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration: %w", classifyError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", classifyError(err))
+	}
+	return nil
+}
+
+// Up applies every migration that has not been applied yet, in version
+// order, and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		// Left in place, so an older build can roll back a newer deployment
+		latest := m.migrations[len(m.migrations)-1].Version
+		for version := range done {
+			if version > latest {
+				log.Printf("⚠️  Database has migration %d, newer than this build knows about", version)
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			record := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+			if err := run(ctx, conn, migration.up, record, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("⬆️  Applied migration %d_%s", migration.Version, migration.Name)
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Down rolls back the latest steps applied migrations, newest first, and
+// returns how many were rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			record := `DELETE FROM schema_migrations WHERE version = $1`
+			if err := run(ctx, conn, migration.down, record, migration.Version); err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("⬇️  Rolled back migration %d_%s", migration.Version, migration.Name)
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Status lists every known migration and when it was applied, if it was
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				status.AppliedAt = sql.NullTime{Time: appliedAt, Valid: true}
+			}
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
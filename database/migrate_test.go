@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteMigrationsAdoptUnversionedFiles(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tickets.db")
+
+	// A file created by the schema script that preceded the migrations
+	legacy, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	initial, err := migrationFiles.ReadFile("sqlite_migrations/0001_create_schema.up.sql")
+	if err != nil {
+		t.Fatalf("failed to read migration: %v", err)
+	}
+	if _, err := legacy.Exec(string(initial)); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	if _, err := legacy.Exec(`INSERT INTO ticket_events (ticket_id, event_type, actor) VALUES ('t-1', 'created', 'alice')`); err != nil {
+		t.Fatalf("failed to record legacy event: %v", err)
+	}
+	legacy.Close()
+
+	db, err := NewConnection(Config{Driver: DriverSQLite, Path: path})
+	if err != nil {
+		t.Fatalf("failed to open legacy file: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Later migrations are applied, and the existing history kept
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM ticket_events WHERE ticket IS NULL`).Scan(&count); err != nil {
+		t.Fatalf("ticket_events was not migrated: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("legacy events = %d, want 1", count)
+	}
+
+	migrator, err := NewSQLiteMigrator(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteMigrator: %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.AppliedAt.Valid {
+			t.Errorf("migration %d_%s is pending", s.Version, s.Name)
+		}
+	}
+
+	// Every migration rolls back and applies again
+	if n, err := migrator.Down(ctx, len(statuses)); err != nil || n != len(statuses) {
+		t.Fatalf("Down() = %d, %v, want %d", n, err, len(statuses))
+	}
+	if n, err := migrator.Up(ctx); err != nil || n != len(statuses) {
+		t.Fatalf("Up() = %d, %v, want %d", n, err, len(statuses))
+	}
+}
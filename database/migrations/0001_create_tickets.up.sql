@@ -1,6 +1,3 @@
--- Database initialization script for ticket service
--- Create the tickets table
-
 CREATE TABLE IF NOT EXISTS tickets (
     id VARCHAR(255) PRIMARY KEY,
     title VARCHAR(500) NOT NULL,
@@ -14,12 +11,8 @@ CREATE TABLE IF NOT EXISTS tickets (
     reporter_id VARCHAR(255) NOT NULL
 );
 
--- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_tickets_status ON tickets(status);
 CREATE INDEX IF NOT EXISTS idx_tickets_priority ON tickets(priority);
 CREATE INDEX IF NOT EXISTS idx_tickets_assignee_id ON tickets(assignee_id);
 CREATE INDEX IF NOT EXISTS idx_tickets_reporter_id ON tickets(reporter_id);
 CREATE INDEX IF NOT EXISTS idx_tickets_created_at ON tickets(created_at);
-
--- Grant necessary permissions to the database user
-GRANT ALL PRIVILEGES ON TABLE tickets TO ayushpandya; 
\ No newline at end of file
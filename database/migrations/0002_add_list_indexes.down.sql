@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tickets_tags;
+DROP INDEX IF EXISTS idx_tickets_updated_at_id;
+DROP INDEX IF EXISTS idx_tickets_created_at_id;
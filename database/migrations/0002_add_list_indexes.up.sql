@@ -0,0 +1,4 @@
+-- Keyset pagination by creation and update time, and tag filters
+CREATE INDEX IF NOT EXISTS idx_tickets_created_at_id ON tickets(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_tickets_updated_at_id ON tickets(updated_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_tickets_tags ON tickets USING GIN (tags);
@@ -0,0 +1,2 @@
+-- Optimistic concurrency version, incremented on every update
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE tickets DROP COLUMN IF EXISTS closed_at;
+ALTER TABLE tickets DROP COLUMN IF EXISTS resolved_at;
+ALTER TABLE tickets DROP COLUMN IF EXISTS resolution_note;
@@ -0,0 +1,4 @@
+-- Status workflow fields, maintained by the service on transitions
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS resolution_note TEXT;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,12 @@
+-- Change history, written in the same transaction as every ticket write.
+-- No foreign key: history is kept after a ticket is deleted.
+CREATE TABLE IF NOT EXISTS ticket_events (
+    id BIGSERIAL PRIMARY KEY,
+    ticket_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_events_ticket_id ON ticket_events(ticket_id, id);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS ticket_events_notify ON ticket_events;
+DROP FUNCTION IF EXISTS notify_ticket_event();
@@ -0,0 +1,12 @@
+-- Publish the ID of every committed ticket event for WatchTickets subscribers
+CREATE OR REPLACE FUNCTION notify_ticket_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('ticket_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ticket_events_notify ON ticket_events;
+CREATE TRIGGER ticket_events_notify
+    AFTER INSERT ON ticket_events
+    FOR EACH ROW EXECUTE FUNCTION notify_ticket_event();
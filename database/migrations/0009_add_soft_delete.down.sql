@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_tickets_deleted_at_id;
+ALTER TABLE tickets DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+-- Soft delete: set while the ticket is in the trash, purged after the retention period
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_tickets_deleted_at_id ON tickets(deleted_at DESC, id DESC) WHERE deleted_at IS NOT NULL;
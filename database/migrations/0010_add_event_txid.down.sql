@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ticket_events_txid_id;
+ALTER TABLE ticket_events DROP COLUMN IF EXISTS txid;
@@ -0,0 +1,7 @@
+-- Event IDs are handed out on insert, not on commit, so change feeds order
+-- events by the transaction that recorded them. Existing events share txid 0,
+-- which keeps cursors issued before this migration valid.
+ALTER TABLE ticket_events ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT '0';
+ALTER TABLE ticket_events ALTER COLUMN txid SET DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_ticket_events_txid_id ON ticket_events(txid, id);
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// eventChannel is the NOTIFY channel the ticket_events trigger publishes new events on
+const eventChannel = "ticket_events"
+
+// EventListener learns of ticket events as they are committed, using
+// PostgreSQL LISTEN/NOTIFY on a dedicated connection
+type EventListener struct {
+	listener *pq.Listener
+}
+
+// NewEventListener opens a listening connection for ticket events
+func NewEventListener(config Config) (*EventListener, error) {
+	listener := pq.NewListener(config.dsn(), time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("⚠️  Ticket event listener: %v", err)
+		}
+	})
+
+	if err := listener.Listen(eventChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen for ticket events: %w", err)
+	}
+
+	return &EventListener{listener: listener}, nil
+}
+
+// Run calls fn for every ticket event committed until ctx is done, and after
+// the connection has been re-established, when events may have been missed.
+// The notifications carry event IDs, but those are handed out on insert and
+// need not increase in commit order, so readers catch up with EventsAfter
+// by EventPosition instead.
+func (l *EventListener) Run(ctx context.Context, fn func()) {
+	pingTicker := time.NewTicker(90 * time.Second)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.listener.Notify:
+			fn()
+		case <-pingTicker.C:
+			// Detect dead connections that would otherwise go unnoticed
+			go l.listener.Ping()
+		}
+	}
+}
+
+// Close closes the listening connection
+func (l *EventListener) Close() error {
+	return l.listener.Close()
+}
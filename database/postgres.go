@@ -6,27 +6,48 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// Drivers selectable with Config.Driver
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // Config holds database configuration
 type Config struct {
+	Driver   string // DriverPostgres, the default, or DriverSQLite
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+	Path     string // SQLite database file, or ":memory:"
 }
 
-// NewConnection creates a new PostgreSQL connection
+// dsn returns the lib/pq connection string for the configuration
+func (c Config) dsn() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
+}
+
+// NewConnection opens the database selected by config.Driver: PostgreSQL,
+// or a SQLite file whose schema is created when missing
 func NewConnection(config Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode)
+	switch config.Driver {
+	case "", DriverPostgres:
+	case DriverSQLite:
+		return openSQLite(config.Path)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open("postgres", config.dsn())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -60,6 +81,60 @@ type Ticket struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	ReporterID  string
+	Version     int64
+
+	// Workflow fields, maintained by status transitions
+	ResolutionNote sql.NullString
+	ResolvedAt     sql.NullTime
+	ClosedAt       sql.NullTime
+
+	// DeletedAt is set while the ticket is in the trash
+	DeletedAt sql.NullTime
+}
+
+// ticketColumns lists the columns scanTicket reads, in order
+const ticketColumns = `id, title, description, status, priority, assignee_id, tags, created_at, updated_at, reporter_id, version,
+	resolution_note, resolved_at, closed_at, deleted_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTicket reads a row selected with ticketColumns, followed by any extra columns
+func scanTicket(row rowScanner, extra ...interface{}) (*Ticket, error) {
+	var ticket Ticket
+	var tagsJSON sql.NullString
+	dest := append([]interface{}{
+		&ticket.ID,
+		&ticket.Title,
+		&ticket.Description,
+		&ticket.Status,
+		&ticket.Priority,
+		&ticket.AssigneeID,
+		&tagsJSON,
+		&ticket.CreatedAt,
+		&ticket.UpdatedAt,
+		&ticket.ReporterID,
+		&ticket.Version,
+		&ticket.ResolutionNote,
+		&ticket.ResolvedAt,
+		&ticket.ClosedAt,
+		&ticket.DeletedAt,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	// Unmarshal tags from JSON
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &ticket.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags from JSON: %w", err)
+		}
+	}
+
+	return &ticket, nil
 }
 
 // TicketRepository handles ticket database operations
@@ -72,12 +147,48 @@ func NewTicketRepository(db *sql.DB) *TicketRepository {
 	return &TicketRepository{db: db}
 }
 
-// Create creates a new ticket
-func (r *TicketRepository) Create(ctx context.Context, ticket *Ticket) (*Ticket, error) {
+// withTx runs fn in a transaction, committing if it returns nil and rolling back otherwise
+func (r *TicketRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err))
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", classifyError(err))
+	}
+	return nil
+}
+
+// getForUpdate reads a ticket that is not deleted and locks its row until tx ends
+func getForUpdate(ctx context.Context, tx *sql.Tx, id string) (*Ticket, error) {
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	ticket, err := scanTicket(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound(id)
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", classifyError(err))
+	}
+
+	return ticket, nil
+}
+
+// Create creates a new ticket and records it in the ticket history
+func (r *TicketRepository) Create(ctx context.Context, ticket *Ticket) (created *Ticket, err error) {
+	ctx, span := startSpan(ctx, "create_ticket", "INSERT")
+	defer func() { endSpan(span, 1, err) }()
+
 	query := `
 		INSERT INTO tickets (id, title, description, status, priority, assignee_id, tags, created_at, updated_at, reporter_id)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id, created_at, updated_at`
+		RETURNING id, created_at, updated_at, version`
 
 	var createdTicket Ticket
 	createdTicket.ID = ticket.ID
@@ -98,117 +209,148 @@ func (r *TicketRepository) Create(ctx context.Context, ticket *Ticket) (*Ticket,
 		return nil, fmt.Errorf("failed to marshal tags to JSON: %w", err)
 	}
 
-	err = r.db.QueryRowContext(ctx, query,
-		createdTicket.ID,
-		ticket.Title,
-		ticket.Description,
-		ticket.Status,
-		ticket.Priority,
-		ticket.AssigneeID,
-		string(tagsJSON),
-		createdTicket.CreatedAt,
-		createdTicket.UpdatedAt,
-		createdTicket.ReporterID,
-	).Scan(&createdTicket.ID, &createdTicket.CreatedAt, &createdTicket.UpdatedAt)
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query,
+			createdTicket.ID,
+			ticket.Title,
+			ticket.Description,
+			ticket.Status,
+			ticket.Priority,
+			ticket.AssigneeID,
+			string(tagsJSON),
+			createdTicket.CreatedAt,
+			createdTicket.UpdatedAt,
+			createdTicket.ReporterID,
+		).Scan(&createdTicket.ID, &createdTicket.CreatedAt, &createdTicket.UpdatedAt, &createdTicket.Version)
 
+		if err != nil {
+			return fmt.Errorf("failed to create ticket: %w", classifyError(err))
+		}
+
+		return recordEvent(ctx, tx, createdTicket.ID, EventCreated, diffTickets(nil, &createdTicket), &createdTicket)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ticket: %w", err)
+		return nil, err
 	}
 
 	return &createdTicket, nil
 }
 
-// GetByID retrieves a ticket by ID
+// GetByID retrieves a ticket by ID. Tickets in the trash are not found.
 func (r *TicketRepository) GetByID(ctx context.Context, id string) (*Ticket, error) {
-	query := `
-		SELECT id, title, description, status, priority, assignee_id, tags, created_at, updated_at
-		FROM tickets WHERE id = $1`
+	return r.getByID(ctx, id, false)
+}
 
-	var ticket Ticket
-	var tagsJSON string
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&ticket.ID,
-		&ticket.Title,
-		&ticket.Description,
-		&ticket.Status,
-		&ticket.Priority,
-		&ticket.AssigneeID,
-		&tagsJSON,
-		&ticket.CreatedAt,
-		&ticket.UpdatedAt,
-	)
+// GetByIDIncludingDeleted retrieves a ticket by ID even if it is in the trash
+func (r *TicketRepository) GetByIDIncludingDeleted(ctx context.Context, id string) (*Ticket, error) {
+	return r.getByID(ctx, id, true)
+}
+
+func (r *TicketRepository) getByID(ctx context.Context, id string, includeDeleted bool) (ticket *Ticket, err error) {
+	ctx, span := startSpan(ctx, "get_ticket", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
 
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE id = $1`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+
+	ticket, err = scanTicket(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("ticket not found: %s", id)
+			return nil, notFound(id)
 		}
-		return nil, fmt.Errorf("failed to get ticket: %w", err)
+		return nil, fmt.Errorf("failed to get ticket: %w", classifyError(err))
 	}
 
-	// Unmarshal tags from JSON
-	if tagsJSON != "" {
-		err = json.Unmarshal([]byte(tagsJSON), &ticket.Tags)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags from JSON: %w", err)
-		}
+	return ticket, nil
+}
+
+// List retrieves tickets matching the query, starting strictly after the given cursor.
+// A nil cursor starts from the first ticket in the requested order.
+func (r *TicketRepository) List(ctx context.Context, q ListQuery, limit int, after *ListCursor) (tickets []*Ticket, err error) {
+	ctx, span := startSpan(ctx, "list_tickets", "SELECT")
+	defer func() { endSpan(span, len(tickets), err) }()
+
+	var b queryBuilder
+	if err := b.addFilter(q.Filter); err != nil {
+		return nil, err
 	}
 
-	return &ticket, nil
-}
+	sortColumn, cursorValue, err := sortExpr(q.SortBy, after)
+	if err != nil {
+		return nil, err
+	}
 
-// List retrieves all tickets with pagination
-func (r *TicketRepository) List(ctx context.Context, limit, offset int) ([]*Ticket, error) {
-	query := `
-		SELECT id, title, description, status, priority, assignee_id, tags, created_at, updated_at
+	direction, comparison := "DESC", "<"
+	if q.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if after != nil {
+		b.add(fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, comparison), cursorValue, after.ID)
+	}
+
+	b.args = append(b.args, limit)
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM tickets
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2`
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT $%d`,
+		ticketColumns, b.where(), sortColumn, direction, direction, len(b.args))
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, b.args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tickets: %w", err)
+		return nil, fmt.Errorf("failed to list tickets: %w", classifyError(err))
 	}
 	defer rows.Close()
 
-	var tickets []*Ticket
 	for rows.Next() {
-		var ticket Ticket
-		var tagsJSON string
-		err := rows.Scan(
-			&ticket.ID,
-			&ticket.Title,
-			&ticket.Description,
-			&ticket.Status,
-			&ticket.Priority,
-			&ticket.AssigneeID,
-			&tagsJSON,
-			&ticket.CreatedAt,
-			&ticket.UpdatedAt,
-		)
+		ticket, err := scanTicket(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan ticket: %w", err)
 		}
-
-		// Unmarshal tags from JSON
-		if tagsJSON != "" {
-			err = json.Unmarshal([]byte(tagsJSON), &ticket.Tags)
-			if err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tags from JSON: %w", err)
-			}
-		}
-
-		tickets = append(tickets, &ticket)
+		tickets = append(tickets, ticket)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate tickets: %w", err)
+		return nil, fmt.Errorf("failed to iterate tickets: %w", classifyError(err))
 	}
 
 	return tickets, nil
 }
 
-// Update updates an existing ticket
-func (r *TicketRepository) Update(ctx context.Context, id string, updates map[string]interface{}) (*Ticket, error) {
+// Update updates an existing ticket. Only the columns present in updates are
+// changed; a nil value sets a nullable column to NULL and clears tags.
+// A non-zero expectedVersion makes the update fail with ErrVersionConflict
+// unless the ticket is still at that version.
+func (r *TicketRepository) Update(ctx context.Context, id string, updates map[string]interface{}, expectedVersion int64) (updated *Ticket, err error) {
+	ctx, span := startSpan(ctx, "update_ticket", "UPDATE")
+	defer func() { endSpan(span, 1, err) }()
+
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := getForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && before.Version != expectedVersion {
+			return versionConflict(id, before.Version, expectedVersion)
+		}
+
+		updated, err = updateLocked(ctx, tx, before, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// updateLocked applies updates to a ticket already locked by tx and records
+// the change in the ticket history
+func updateLocked(ctx context.Context, tx *sql.Tx, before *Ticket, updates map[string]interface{}) (*Ticket, error) {
 	// Build dynamic query based on provided updates
 	setParts := []string{}
 	args := []interface{}{}
@@ -216,97 +358,87 @@ func (r *TicketRepository) Update(ctx context.Context, id string, updates map[st
 
 	for field, value := range updates {
 		switch field {
-		case "title", "description", "status", "priority", "assignee_id":
+		case "title", "status", "priority":
+			if value == nil {
+				return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("%s cannot be cleared", field)}
+			}
+			setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
+			args = append(args, value)
+			argIndex++
+		case "description", "assignee_id", "resolution_note", "resolved_at", "closed_at":
 			setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
 			args = append(args, value)
 			argIndex++
 		case "tags":
 			setParts = append(setParts, fmt.Sprintf("tags = $%d", argIndex))
+			tags, _ := value.([]string)
+			if tags == nil {
+				tags = []string{}
+			}
 			// Convert tags to JSON
-			tagsJSON, err := json.Marshal(value.([]string))
+			tagsJSON, err := json.Marshal(tags)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal tags to JSON: %w", err)
 			}
 			args = append(args, string(tagsJSON))
 			argIndex++
+		default:
+			return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("unknown field %s", field)}
 		}
 	}
 
 	if len(setParts) == 0 {
-		return r.GetByID(ctx, id) // No updates, return existing ticket
+		return before, nil // No updates, return existing ticket
 	}
 
+	setParts = append(setParts, "version = version + 1")
 	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
-	args = append(args, time.Now())
+	args = append(args, timeNow())
 	argIndex++
 
-	// Simplified approach - rebuild the query properly
-	setClause := ""
-	for i, part := range setParts {
-		if i > 0 {
-			setClause += ", "
-		}
-		setClause += part
-	}
-
 	query := fmt.Sprintf(`
-		UPDATE tickets 
+		UPDATE tickets
 		SET %s
 		WHERE id = $%d
-		RETURNING id, title, description, status, priority, assignee_id, tags, created_at, updated_at`,
-		setClause, argIndex)
-
-	args = append(args, id)
-
-	var ticket Ticket
-	var tagsJSON string
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(
-		&ticket.ID,
-		&ticket.Title,
-		&ticket.Description,
-		&ticket.Status,
-		&ticket.Priority,
-		&ticket.AssigneeID,
-		&tagsJSON,
-		&ticket.CreatedAt,
-		&ticket.UpdatedAt,
-	)
+		RETURNING %s`,
+		strings.Join(setParts, ", "), argIndex, ticketColumns)
+	args = append(args, before.ID)
 
+	updated, err := scanTicket(tx.QueryRowContext(ctx, query, args...))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("ticket not found: %s", id)
-		}
-		return nil, fmt.Errorf("failed to update ticket: %w", err)
+		return nil, fmt.Errorf("failed to update ticket: %w", classifyError(err))
 	}
 
-	// Unmarshal tags from JSON
-	if tagsJSON != "" {
-		err = json.Unmarshal([]byte(tagsJSON), &ticket.Tags)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags from JSON: %w", err)
-		}
+	if err := recordEvent(ctx, tx, before.ID, EventUpdated, diffTickets(before, updated), updated); err != nil {
+		return nil, err
 	}
 
-	return &ticket, nil
+	return updated, nil
 }
 
-// Delete deletes a ticket by ID
-func (r *TicketRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM tickets WHERE id = $1`
-
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete ticket: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+// Delete moves a ticket to the trash by setting deleted_at, and records its
+// final state in the ticket history. The ticket can be brought back with
+// Restore until PurgeDeleted removes it for good. A non-zero expectedVersion
+// makes the delete fail with ErrVersionConflict unless the ticket is still
+// at that version.
+func (r *TicketRepository) Delete(ctx context.Context, id string, expectedVersion int64) (err error) {
+	ctx, span := startSpan(ctx, "delete_ticket", "UPDATE")
+	defer func() { endSpan(span, 1, err) }()
+
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := getForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && before.Version != expectedVersion {
+			return versionConflict(id, before.Version, expectedVersion)
+		}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("ticket not found: %s", id)
-	}
+		query := `UPDATE tickets SET deleted_at = $1, version = version + 1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, timeNow(), id); err != nil {
+			return fmt.Errorf("failed to delete ticket: %w", classifyError(err))
+		}
 
-	return nil
+		return recordEvent(ctx, tx, id, EventDeleted, diffTickets(before, nil), nil)
+	})
 }
@@ -0,0 +1,328 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ephemeralPostgres is the PostgreSQL server for the tests of this package:
+// the one named by TEST_DB_HOST, or else a throwaway cluster. It is set up on
+// first use and cleaned up by TestMain, so every database created for the
+// tests disappears with it.
+var ephemeralPostgres struct {
+	once      sync.Once
+	skip      string
+	err       error
+	dir       string
+	pgCtl     string
+	config    Config
+	admin     *sql.DB
+	template  string // migrated once and copied for every test
+	databases atomic.Int64
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	stopPostgres()
+	os.Exit(code)
+}
+
+func TestTicketRepository(t *testing.T) {
+	postgresConfig(t)
+	testTicketStore(t, func(t *testing.T) TicketStore {
+		return NewTicketRepository(newPostgresDB(t))
+	})
+}
+
+// TestEventsCommittedOutOfOrder commits a transaction that recorded its event
+// first after one that recorded its event later, so the event with the lower
+// ID becomes visible last. Readers must not have moved past it by then.
+func TestEventsCommittedOutOfOrder(t *testing.T) {
+	db := newPostgresDB(t)
+	repo := NewTicketRepository(db)
+	ctx := context.Background()
+
+	start, err := repo.EventHorizon(ctx)
+	if err != nil {
+		t.Fatalf("EventHorizon: %v", err)
+	}
+
+	first, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer first.Rollback()
+	if err := recordEvent(ctx, first, "ticket-first", EventCreated, nil, nil); err != nil {
+		t.Fatalf("recordEvent: %v", err)
+	}
+
+	second, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer second.Rollback()
+	if err := recordEvent(ctx, second, "ticket-second", EventCreated, nil, nil); err != nil {
+		t.Fatalf("recordEvent: %v", err)
+	}
+	if err := second.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	events, err := repo.EventsAfter(ctx, start, 10)
+	if err != nil {
+		t.Fatalf("EventsAfter: %v", err)
+	}
+	if len(events) != 1 || events[0].TicketID != "ticket-second" {
+		t.Fatalf("EventsAfter() = %v, want only the committed event", events)
+	}
+	horizon, err := repo.EventHorizon(ctx)
+	if err != nil {
+		t.Fatalf("EventHorizon: %v", err)
+	}
+	if !horizon.Before(events[0].Position()) {
+		t.Errorf("horizon %+v covers %+v while an earlier transaction is in flight", horizon, events[0].Position())
+	}
+
+	if err := first.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	events, err = repo.EventsAfter(ctx, start, 10)
+	if err != nil {
+		t.Fatalf("EventsAfter: %v", err)
+	}
+	if len(events) != 2 || events[0].TicketID != "ticket-first" || events[1].TicketID != "ticket-second" {
+		t.Fatalf("EventsAfter() = %v, want both events in transaction order", events)
+	}
+	if events[0].ID > events[1].ID {
+		t.Errorf("event IDs %d and %d are not in insert order", events[0].ID, events[1].ID)
+	}
+	horizon, err = repo.EventHorizon(ctx)
+	if err != nil {
+		t.Fatalf("EventHorizon: %v", err)
+	}
+	if horizon.Before(events[1].Position()) {
+		t.Errorf("horizon %+v does not cover %+v after every transaction ended", horizon, events[1].Position())
+	}
+}
+
+// postgresBinary finds a PostgreSQL program in PG_BIN, or on PATH
+func postgresBinary(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		return exec.LookPath(filepath.Join(dir, name))
+	}
+	return exec.LookPath(name)
+}
+
+// postgresConfig sets up the server if needed and returns the configuration
+// of its maintenance database, skipping t when no server is available
+func postgresConfig(t *testing.T) Config {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping PostgreSQL in short mode")
+	}
+
+	ephemeralPostgres.once.Do(func() {
+		ephemeralPostgres.skip, ephemeralPostgres.err = setUpPostgres()
+	})
+	if ephemeralPostgres.skip != "" {
+		t.Skip(ephemeralPostgres.skip)
+	}
+	if ephemeralPostgres.err != nil {
+		t.Fatalf("failed to set up PostgreSQL: %v", ephemeralPostgres.err)
+	}
+	return ephemeralPostgres.config
+}
+
+// newPostgresDB creates a migrated database of its own for t
+func newPostgresDB(t *testing.T) *sql.DB {
+	t.Helper()
+	config := postgresConfig(t)
+	config.DBName = fmt.Sprintf("%s_%d", ephemeralPostgres.template, ephemeralPostgres.databases.Add(1))
+
+	// The template cannot be copied while a connection to it is still closing
+	query := fmt.Sprintf(`CREATE DATABASE %s TEMPLATE %s`, config.DBName, ephemeralPostgres.template)
+	for attempt := 0; ; attempt++ {
+		_, err := ephemeralPostgres.admin.Exec(query)
+		var pqErr *pq.Error
+		if err == nil {
+			break
+		}
+		if !errors.As(err, &pqErr) || pqErr.Code != "55006" || attempt == 50 { // object_in_use
+			t.Fatalf("failed to create database: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	db, err := NewConnection(config)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", config.DBName, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testServerConfig returns the server named by the TEST_DB_* variables, which
+// take the defaults of the service's DB_* variables
+func testServerConfig() (Config, bool) {
+	host := os.Getenv("TEST_DB_HOST")
+	if host == "" {
+		return Config{}, false
+	}
+	getEnv := func(key, fallback string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return fallback
+	}
+	return Config{
+		Host:     host,
+		Port:     getEnv("TEST_DB_PORT", "5432"),
+		User:     getEnv("TEST_DB_USER", "postgres"),
+		Password: getEnv("TEST_DB_PASSWORD", "postgres"),
+		DBName:   getEnv("TEST_DB_NAME", "postgres"),
+		SSLMode:  getEnv("TEST_DB_SSLMODE", "disable"),
+	}, true
+}
+
+// setUpPostgres connects to the server named by TEST_DB_HOST, or starts a
+// cluster when there is none, then migrates the template database. It
+// returns a reason to skip when no server is available.
+func setUpPostgres() (string, error) {
+	config, ok := testServerConfig()
+	if ok {
+		// Names of their own, so that runs sharing the server do not collide
+		ephemeralPostgres.template = fmt.Sprintf("conformance_%d_%d", os.Getpid(), time.Now().Unix())
+	} else {
+		skip, err := startPostgres()
+		if skip != "" || err != nil {
+			return skip, err
+		}
+		config = ephemeralPostgres.config
+		ephemeralPostgres.template = "conformance_template"
+	}
+
+	admin, err := NewConnection(config)
+	if err != nil {
+		return "", err
+	}
+	ephemeralPostgres.admin = admin
+	ephemeralPostgres.config = config
+
+	if _, err := admin.Exec(`CREATE DATABASE ` + ephemeralPostgres.template); err != nil {
+		return "", fmt.Errorf("failed to create template database: %w", err)
+	}
+
+	config.DBName = ephemeralPostgres.template
+	template, err := NewConnection(config)
+	if err != nil {
+		return "", err
+	}
+	defer template.Close()
+
+	migrator, err := NewMigrator(template)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := migrator.Up(ctx); err != nil {
+		return "", fmt.Errorf("failed to migrate template database: %w", err)
+	}
+
+	return "", nil
+}
+
+// startPostgres initializes and starts a cluster in a temporary directory.
+// It returns a reason to skip when the PostgreSQL binaries are missing.
+func startPostgres() (string, error) {
+	initdb, err := postgresBinary("initdb")
+	if err != nil {
+		return "no PostgreSQL server: set TEST_DB_HOST, or add the PostgreSQL binaries to PATH or set PG_BIN", nil
+	}
+	pgCtl, err := postgresBinary("pg_ctl")
+	if err != nil {
+		return "no PostgreSQL server: set TEST_DB_HOST, or add pg_ctl to PATH or PG_BIN", nil
+	}
+
+	dir, err := os.MkdirTemp("", "ticketdb-test-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cluster directory: %w", err)
+	}
+	ephemeralPostgres.dir = dir
+
+	data := filepath.Join(dir, "data")
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust", "--no-sync", "--no-locale", "-E", "UTF8").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("initdb failed: %w\n%s", err, out)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %w", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	// Durability is pointless for a cluster deleted after the tests
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c full_page_writes=off", port, dir)
+	out, err = exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("pg_ctl start failed: %w\n%s", err, out)
+	}
+	ephemeralPostgres.pgCtl = pgCtl
+
+	// Trust authentication ignores the password, which lib/pq still needs to parse the DSN
+	ephemeralPostgres.config = Config{
+		Host:     "127.0.0.1",
+		Port:     strconv.Itoa(port),
+		User:     "postgres",
+		Password: "postgres",
+		DBName:   "postgres",
+		SSLMode:  "disable",
+	}
+
+	return "", nil
+}
+
+// stopPostgres drops the databases created on a server named by TEST_DB_HOST,
+// or stops the cluster, if one was started, and removes its files
+func stopPostgres() {
+	if admin := ephemeralPostgres.admin; admin != nil {
+		if ephemeralPostgres.pgCtl == "" {
+			for i := ephemeralPostgres.databases.Load(); i > 0; i-- {
+				dropTestDatabase(admin, fmt.Sprintf("%s_%d", ephemeralPostgres.template, i))
+			}
+			dropTestDatabase(admin, ephemeralPostgres.template)
+		}
+		admin.Close()
+	}
+	if ephemeralPostgres.pgCtl != "" {
+		data := filepath.Join(ephemeralPostgres.dir, "data")
+		if out, err := exec.Command(ephemeralPostgres.pgCtl, "-D", data, "-m", "immediate", "stop").CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "pg_ctl stop failed: %v\n%s", err, out)
+		}
+	}
+	if ephemeralPostgres.dir != "" {
+		os.RemoveAll(ephemeralPostgres.dir)
+	}
+}
+
+func dropTestDatabase(admin *sql.DB, name string) {
+	if _, err := admin.Exec(`DROP DATABASE IF EXISTS ` + name + ` WITH (FORCE)`); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to drop database %s: %v\n", name, err)
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Matches are delimited by control characters while a snippet is built,
+// which renderSnippet turns into <b></b> once the ticket's text is escaped
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+// headlineOptions controls the snippets produced by ts_headline
+const headlineOptions = `StartSel=` + snippetStart + `, StopSel=` + snippetStop + `, MaxWords=35, MinWords=15, MaxFragments=2`
+
+// renderSnippet HTML-escapes a snippet and wraps its matches in <b></b>.
+// Delimiters that the ticket's own text contains are kept balanced, so the
+// result holds no markup but <b> elements.
+func renderSnippet(s string) string {
+	var b strings.Builder
+	open := false
+	for {
+		i := strings.IndexAny(s, snippetStart+snippetStop)
+		if i < 0 {
+			b.WriteString(html.EscapeString(s))
+			break
+		}
+		b.WriteString(html.EscapeString(s[:i]))
+		switch {
+		case s[i:i+1] == snippetStart && !open:
+			b.WriteString("<b>")
+			open = true
+		case s[i:i+1] == snippetStop && open:
+			b.WriteString("</b>")
+			open = false
+		}
+		s = s[i+1:]
+	}
+	if open {
+		b.WriteString("</b>")
+	}
+	return b.String()
+}
+
+// SearchResult is a ticket matched by Search along with its relevance
+type SearchResult struct {
+	Ticket             *Ticket
+	Rank               float64
+	TitleSnippet       string
+	DescriptionSnippet string
+}
+
+// Search ranks tickets by relevance of their title and description to free text.
+// Results are ordered by rank, then ID, and paged with the Rank and ID of the cursor.
+func (r *TicketRepository) Search(ctx context.Context, text string, filter ListFilter, limit int, after *ListCursor) (results []*SearchResult, err error) {
+	ctx, span := startSpan(ctx, "search_tickets", "SELECT")
+	defer func() { endSpan(span, len(results), err) }()
+
+	var b queryBuilder
+	// $1 is the search text, referenced again by the rank and headline expressions
+	b.add("search_vector @@ websearch_to_tsquery('english', ?)", text)
+	if err := b.addFilter(filter); err != nil {
+		return nil, err
+	}
+
+	rankExpr := "ts_rank(search_vector, websearch_to_tsquery('english', $1))"
+	if after != nil {
+		b.add(fmt.Sprintf("(%s, id) < (?::real, ?)", rankExpr), after.Rank, after.ID)
+	}
+
+	b.args = append(b.args, limit)
+	query := fmt.Sprintf(`
+		SELECT %s,
+			%s AS rank,
+			ts_headline('english', title, websearch_to_tsquery('english', $1), '%s'),
+			ts_headline('english', coalesce(description, ''), websearch_to_tsquery('english', $1), '%s')
+		FROM tickets
+		%s
+		ORDER BY rank DESC, id DESC
+		LIMIT $%d`,
+		ticketColumns, rankExpr, headlineOptions, headlineOptions, b.where(), len(b.args))
+
+	rows, err := r.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tickets: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result SearchResult
+		ticket, err := scanTicket(rows, &result.Rank, &result.TitleSnippet, &result.DescriptionSnippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		result.Ticket = ticket
+		result.TitleSnippet = renderSnippet(result.TitleSnippet)
+		result.DescriptionSnippet = renderSnippet(result.DescriptionSnippet)
+		results = append(results, &result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", classifyError(err))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,505 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqlitePragmas are set on every connection: foreign keys for ON DELETE
+// CASCADE, WAL so readers do not block the writer, and a wait for the write
+// lock instead of failing with SQLITE_BUSY
+var sqlitePragmas = []string{"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)"}
+
+// openSQLite opens a SQLite database with the pure-Go driver and applies its
+// pending schema migrations
+func openSQLite(path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, errors.New("failed to open database: SQLite path is required")
+	}
+
+	params := url.Values{}
+	for _, pragma := range sqlitePragmas {
+		params.Add("_pragma", pragma)
+	}
+	// Transactions take the write lock when they begin, so they never fail
+	// upgrading a read lock halfway through
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Every connection to :memory: would open a database of its own
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	migrator, err := NewSQLiteMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	log.Printf("✅ SQLite database opened at %s", path)
+	return db, nil
+}
+
+// classifySQLiteError maps SQLite errors onto the repository's sentinel errors
+func classifySQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	var kind error
+	switch code := sqliteErr.Code(); {
+	case code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, code == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		kind = ErrAlreadyExists
+	case code == sqlite3.SQLITE_CONSTRAINT_NOTNULL, code == sqlite3.SQLITE_CONSTRAINT_CHECK:
+		kind = ErrInvalidArgument
+	case code == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		kind = ErrFailedPrecondition
+	case code&0xff == sqlite3.SQLITE_BUSY, // extended codes keep the primary code in the low byte
+		code&0xff == sqlite3.SQLITE_LOCKED,
+		code&0xff == sqlite3.SQLITE_IOERR,
+		code&0xff == sqlite3.SQLITE_FULL,
+		code&0xff == sqlite3.SQLITE_CANTOPEN:
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	default:
+		return err
+	}
+
+	// Messages end with "constraint failed: tickets.title" for NOT NULL and
+	// UNIQUE, or the constraint name, which is the column, for CHECK
+	constraint := sqliteErr.Error()
+	if i := strings.LastIndex(constraint, "constraint failed: "); i >= 0 {
+		constraint = constraint[i+len("constraint failed: "):]
+	}
+	constraint, _, _ = strings.Cut(constraint, " (")
+	// Like PostgreSQL, only invalid values are reported against a column
+	var column string
+	if kind == ErrInvalidArgument {
+		column = constraint[strings.LastIndex(constraint, ".")+1:]
+	}
+
+	return &ConstraintError{Kind: kind, Column: column, Constraint: constraint, Err: err}
+}
+
+// toMicros converts a timestamp to the microseconds since the epoch stored
+// by SQLite, rounded like PostgreSQL rounds timestamps
+func toMicros(t time.Time) int64 {
+	return dbTime(t).UnixMicro()
+}
+
+// nullMicros converts an optional timestamp for storage
+func nullMicros(t sql.NullTime) sql.NullInt64 {
+	if !t.Valid {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: toMicros(t.Time), Valid: true}
+}
+
+// fromMicros converts a stored timestamp back to a time
+func fromMicros(us sql.NullInt64) sql.NullTime {
+	if !us.Valid {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.UnixMicro(us.Int64).UTC(), Valid: true}
+}
+
+// sqliteArgs converts the timestamps among query arguments to microseconds
+func sqliteArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = toMicros(v)
+		case sql.NullTime:
+			converted[i] = nullMicros(v)
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+// scanSQLiteTicket reads a row selected with ticketColumns, followed by any extra columns
+func scanSQLiteTicket(row rowScanner, extra ...interface{}) (*Ticket, error) {
+	var ticket Ticket
+	var tagsJSON sql.NullString
+	var createdAt, updatedAt, resolvedAt, closedAt, deletedAt sql.NullInt64
+	dest := append([]interface{}{
+		&ticket.ID,
+		&ticket.Title,
+		&ticket.Description,
+		&ticket.Status,
+		&ticket.Priority,
+		&ticket.AssigneeID,
+		&tagsJSON,
+		&createdAt,
+		&updatedAt,
+		&ticket.ReporterID,
+		&ticket.Version,
+		&ticket.ResolutionNote,
+		&resolvedAt,
+		&closedAt,
+		&deletedAt,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	ticket.CreatedAt = fromMicros(createdAt).Time
+	ticket.UpdatedAt = fromMicros(updatedAt).Time
+	ticket.ResolvedAt = fromMicros(resolvedAt)
+	ticket.ClosedAt = fromMicros(closedAt)
+	ticket.DeletedAt = fromMicros(deletedAt)
+
+	// Unmarshal tags from JSON
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &ticket.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags from JSON: %w", err)
+		}
+	}
+
+	return &ticket, nil
+}
+
+// scanSQLiteTickets reads and closes rows selected with ticketColumns
+func scanSQLiteTickets(rows *sql.Rows) ([]*Ticket, error) {
+	defer rows.Close()
+
+	var tickets []*Ticket
+	for rows.Next() {
+		ticket, err := scanSQLiteTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ticket: %w", err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tickets: %w", classifySQLiteError(err))
+	}
+
+	return tickets, nil
+}
+
+// addSQLiteFilter appends the conditions for a ListFilter, matching tags
+// with json_each where PostgreSQL uses JSONB containment
+func (b *queryBuilder) addSQLiteFilter(filter ListFilter) {
+	tags := filter.Tags
+	filter.Tags = nil
+	b.addFilter(filter) // only tags can fail
+
+	for _, tag := range tags {
+		b.add("EXISTS (SELECT 1 FROM json_each(tickets.tags) WHERE value = ?)", tag)
+	}
+}
+
+// SQLiteRepository handles ticket operations on a SQLite database opened
+// with NewConnection, for single-node deployments. It behaves like
+// TicketRepository, but announces changes to listeners in this process only.
+type SQLiteRepository struct {
+	db   *sql.DB
+	feed eventFeed
+}
+
+// NewSQLiteRepository creates a new SQLite ticket repository
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// NewEventListener starts watching for events committed through this
+// repository from now on
+func (r *SQLiteRepository) NewEventListener() *LocalEventListener {
+	return r.feed.listen()
+}
+
+// sqliteTx is a transaction that collects the IDs of the events it records
+type sqliteTx struct {
+	*sql.Tx
+	events []int64
+}
+
+// withTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. Recorded events are announced once committed.
+func (r *SQLiteRepository) withTx(ctx context.Context, fn func(tx *sqliteTx) error) error {
+	sqlTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifySQLiteError(err))
+	}
+
+	tx := &sqliteTx{Tx: sqlTx}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", classifySQLiteError(err))
+	}
+
+	if len(tx.events) > 0 {
+		r.feed.publish()
+	}
+	return nil
+}
+
+// getLive reads a ticket that is not deleted. The immediate transaction
+// already holds the write lock, so no row lock is needed.
+func getLive(ctx context.Context, tx *sqliteTx, id string) (*Ticket, error) {
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE id = $1 AND deleted_at IS NULL`
+
+	ticket, err := scanSQLiteTicket(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound(id)
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", classifySQLiteError(err))
+	}
+
+	return ticket, nil
+}
+
+// Create creates a new ticket and records it in the ticket history
+func (r *SQLiteRepository) Create(ctx context.Context, ticket *Ticket) (created *Ticket, err error) {
+	ctx, span := startSQLiteSpan(ctx, "create_ticket", "INSERT")
+	defer func() { endSpan(span, 1, err) }()
+
+	batch, err := r.createBatch(ctx, []*Ticket{ticket})
+	if err != nil {
+		return nil, err
+	}
+	return batch[0], nil
+}
+
+// GetByID retrieves a ticket by ID. Tickets in the trash are not found.
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*Ticket, error) {
+	return r.getByID(ctx, id, false)
+}
+
+// GetByIDIncludingDeleted retrieves a ticket by ID even if it is in the trash
+func (r *SQLiteRepository) GetByIDIncludingDeleted(ctx context.Context, id string) (*Ticket, error) {
+	return r.getByID(ctx, id, true)
+}
+
+func (r *SQLiteRepository) getByID(ctx context.Context, id string, includeDeleted bool) (ticket *Ticket, err error) {
+	ctx, span := startSQLiteSpan(ctx, "get_ticket", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
+
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE id = $1`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+
+	ticket, err = scanSQLiteTicket(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound(id)
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", classifySQLiteError(err))
+	}
+
+	return ticket, nil
+}
+
+// List retrieves tickets matching the query, starting strictly after the given cursor.
+// A nil cursor starts from the first ticket in the requested order.
+func (r *SQLiteRepository) List(ctx context.Context, q ListQuery, limit int, after *ListCursor) (tickets []*Ticket, err error) {
+	ctx, span := startSQLiteSpan(ctx, "list_tickets", "SELECT")
+	defer func() { endSpan(span, len(tickets), err) }()
+
+	var b queryBuilder
+	b.addSQLiteFilter(q.Filter)
+
+	sortColumn, cursorValue, err := sortExpr(q.SortBy, after)
+	if err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "DESC", "<"
+	if q.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if after != nil {
+		b.add(fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, comparison), cursorValue, after.ID)
+	}
+
+	b.args = append(b.args, limit)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM tickets
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT $%d`,
+		ticketColumns, b.where(), sortColumn, direction, direction, len(b.args))
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteArgs(b.args)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %w", classifySQLiteError(err))
+	}
+
+	return scanSQLiteTickets(rows)
+}
+
+// Update updates an existing ticket. Only the columns present in updates are
+// changed; a nil value sets a nullable column to NULL and clears tags.
+// A non-zero expectedVersion makes the update fail with ErrVersionConflict
+// unless the ticket is still at that version.
+func (r *SQLiteRepository) Update(ctx context.Context, id string, updates map[string]interface{}, expectedVersion int64) (updated *Ticket, err error) {
+	ctx, span := startSQLiteSpan(ctx, "update_ticket", "UPDATE")
+	defer func() { endSpan(span, 1, err) }()
+
+	err = r.withTx(ctx, func(tx *sqliteTx) error {
+		before, err := getLive(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && before.Version != expectedVersion {
+			return versionConflict(id, before.Version, expectedVersion)
+		}
+
+		updated, err = updateSQLite(ctx, tx, before, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// updateSQLite applies updates to a ticket read in tx and records the change
+// in the ticket history
+func updateSQLite(ctx context.Context, tx *sqliteTx, before *Ticket, updates map[string]interface{}) (*Ticket, error) {
+	var setParts []string
+	var args []interface{}
+
+	for field, value := range updates {
+		switch field {
+		case "title", "status", "priority":
+			if value == nil {
+				return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("%s cannot be cleared", field)}
+			}
+		case "description", "assignee_id", "resolution_note", "resolved_at", "closed_at":
+		case "tags":
+			tags, _ := value.([]string)
+			if tags == nil {
+				tags = []string{}
+			}
+			tagsJSON, err := json.Marshal(tags)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal tags to JSON: %w", err)
+			}
+			value = string(tagsJSON)
+		default:
+			return nil, &ConstraintError{Kind: ErrInvalidArgument, Column: field, Err: fmt.Errorf("unknown field %s", field)}
+		}
+		args = append(args, value)
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", field, len(args)))
+	}
+
+	if len(setParts) == 0 {
+		return before, nil // No updates, return existing ticket
+	}
+
+	args = append(args, timeNow())
+	setParts = append(setParts, "version = version + 1", fmt.Sprintf("updated_at = $%d", len(args)))
+	args = append(args, before.ID)
+
+	query := fmt.Sprintf(`
+		UPDATE tickets
+		SET %s
+		WHERE id = $%d
+		RETURNING %s`,
+		strings.Join(setParts, ", "), len(args), ticketColumns)
+
+	updated, err := scanSQLiteTicket(tx.QueryRowContext(ctx, query, sqliteArgs(args)...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update ticket: %w", classifySQLiteError(err))
+	}
+
+	if err := recordSQLiteEvents(ctx, tx, []pendingEvent{{ticketID: before.ID, eventType: EventUpdated, changes: diffTickets(before, updated), ticket: updated}}); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete moves a ticket to the trash by setting deleted_at, and records its
+// final state in the ticket history
+func (r *SQLiteRepository) Delete(ctx context.Context, id string, expectedVersion int64) (err error) {
+	ctx, span := startSQLiteSpan(ctx, "delete_ticket", "UPDATE")
+	defer func() { endSpan(span, 1, err) }()
+
+	return r.withTx(ctx, func(tx *sqliteTx) error {
+		before, err := getLive(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && before.Version != expectedVersion {
+			return versionConflict(id, before.Version, expectedVersion)
+		}
+
+		query := `UPDATE tickets SET deleted_at = $1, version = version + 1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, toMicros(timeNow()), id); err != nil {
+			return fmt.Errorf("failed to delete ticket: %w", classifySQLiteError(err))
+		}
+
+		return recordSQLiteEvents(ctx, tx, []pendingEvent{{ticketID: id, eventType: EventDeleted, changes: diffTickets(before, nil)}})
+	})
+}
+
+// CountByStatusAndPriority counts the tickets that are not in the trash
+func (r *SQLiteRepository) CountByStatusAndPriority(ctx context.Context) (counts []TicketCount, err error) {
+	ctx, span := startSQLiteSpan(ctx, "count_tickets", "SELECT")
+	defer func() { endSpan(span, len(counts), err) }()
+
+	query := `
+		SELECT status, priority, COUNT(*)
+		FROM tickets
+		WHERE deleted_at IS NULL
+		GROUP BY status, priority`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tickets: %w", classifySQLiteError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c TicketCount
+		if err := rows.Scan(&c.Status, &c.Priority, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ticket counts: %w", classifySQLiteError(err))
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CreateBatch inserts tickets with a single multi-row INSERT and records their
+// history, all in one transaction. Either every ticket is created or none is.
+// Results are returned in the order of the input.
+func (r *SQLiteRepository) CreateBatch(ctx context.Context, tickets []*Ticket) (batch []*Ticket, err error) {
+	ctx, span := startSQLiteSpan(ctx, "create_tickets", "INSERT")
+	defer func() { endSpan(span, len(batch), err) }()
+
+	return r.createBatch(ctx, tickets)
+}
+
+func (r *SQLiteRepository) createBatch(ctx context.Context, tickets []*Ticket) ([]*Ticket, error) {
+	if len(tickets) == 0 {
+		return nil, nil
+	}
+	if len(tickets) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch of %d tickets exceeds %d", ErrInvalidArgument, len(tickets), MaxBatchSize)
+	}
+
+	const columnsPerRow = 10
+	values := make([]string, 0, len(tickets))
+	args := make([]interface{}, 0, len(tickets)*columnsPerRow)
+
+	for i, ticket := range tickets {
+		// Convert tags to JSON
+		tagsJSON, err := json.Marshal(ticket.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags to JSON: %w", err)
+		}
+
+		placeholders := make([]string, columnsPerRow)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*columnsPerRow+j+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+
+		args = append(args,
+			ticket.ID,
+			ticket.Title,
+			ticket.Description,
+			ticket.Status,
+			ticket.Priority,
+			ticket.AssigneeID,
+			string(tagsJSON),
+			toMicros(ticket.CreatedAt),
+			toMicros(ticket.UpdatedAt),
+			ticket.ReporterID,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO tickets (id, title, description, status, priority, assignee_id, tags, created_at, updated_at, reporter_id)
+		VALUES %s
+		RETURNING %s`,
+		strings.Join(values, ", "), ticketColumns)
+
+	created := make(map[string]*Ticket, len(tickets))
+	err := r.withTx(ctx, func(tx *sqliteTx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to create tickets: %w", classifySQLiteError(err))
+		}
+		inserted, err := scanSQLiteTickets(rows)
+		if err != nil {
+			return fmt.Errorf("failed to create tickets: %w", err)
+		}
+		for _, ticket := range inserted {
+			created[ticket.ID] = ticket
+		}
+
+		events := make([]pendingEvent, 0, len(tickets))
+		for _, ticket := range tickets {
+			events = append(events, pendingEvent{
+				ticketID:  ticket.ID,
+				eventType: EventCreated,
+				changes:   diffTickets(nil, created[ticket.ID]),
+				ticket:    created[ticket.ID],
+			})
+		}
+		return recordSQLiteEvents(ctx, tx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Ticket, len(tickets))
+	for i, ticket := range tickets {
+		result[i] = created[ticket.ID]
+	}
+	return result, nil
+}
+
+// GetByIDs retrieves the tickets with the given IDs in a single query, in the
+// order of ids. Tickets that do not exist or are in the trash are left out.
+func (r *SQLiteRepository) GetByIDs(ctx context.Context, ids []string) (tickets []*Ticket, err error) {
+	ctx, span := startSQLiteSpan(ctx, "get_tickets", "SELECT")
+	defer func() { endSpan(span, len(tickets), err) }()
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var b queryBuilder
+	b.add(inList("id", len(ids)), toArgs(ids)...)
+	b.add("deleted_at IS NULL")
+	query := `SELECT ` + ticketColumns + ` FROM tickets ` + b.where()
+
+	rows, err := r.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", classifySQLiteError(err))
+	}
+	unordered, err := scanSQLiteTickets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*Ticket, len(unordered))
+	for _, ticket := range unordered {
+		found[ticket.ID] = ticket
+	}
+
+	tickets = make([]*Ticket, 0, len(found))
+	for _, id := range ids {
+		if ticket, ok := found[id]; ok {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// UpdateBatch updates the tickets with the given IDs, or every ticket matching
+// filter when ids is empty, in a single transaction, with the same atomic and
+// best-effort semantics as TicketRepository.UpdateBatch
+func (r *SQLiteRepository) UpdateBatch(ctx context.Context, ids []string, filter ListFilter, atomic bool, fn BatchUpdateFunc) (results []BatchResult, err error) {
+	ctx, span := startSQLiteSpan(ctx, "update_tickets", "UPDATE")
+	defer func() { endSpan(span, len(results), err) }()
+
+	err = r.withTx(ctx, func(tx *sqliteTx) error {
+		results = nil
+
+		selected, order, err := selectBatch(ctx, tx, ids, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range order {
+			current, ok := selected[id]
+			if !ok {
+				if atomic {
+					return notFound(id)
+				}
+				results = append(results, BatchResult{ID: id, Err: notFound(id)})
+				continue
+			}
+
+			updated, err := updateSQLiteBatchItem(ctx, tx, current, atomic, fn)
+			if err != nil && (atomic || ctx.Err() != nil || errors.Is(err, errSavepointLost)) {
+				return fmt.Errorf("ticket %s: %w", id, err)
+			}
+			results = append(results, BatchResult{ID: id, Ticket: updated, Err: err})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// updateSQLiteBatchItem applies fn to one ticket of a batch. Outside atomic
+// mode the write is wrapped in a savepoint so that its failure leaves tx usable.
+func updateSQLiteBatchItem(ctx context.Context, tx *sqliteTx, current *Ticket, atomic bool, fn BatchUpdateFunc) (*Ticket, error) {
+	updates, err := fn(current)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic {
+		return updateSQLite(ctx, tx, current, updates)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSavepointLost, classifySQLiteError(err))
+	}
+
+	recorded := len(tx.events)
+	updated, err := updateSQLite(ctx, tx, current, updates)
+	if err != nil {
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); rbErr != nil {
+			return nil, fmt.Errorf("%w: %w", errSavepointLost, classifySQLiteError(rbErr))
+		}
+		tx.events = tx.events[:recorded]
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_item`); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSavepointLost, classifySQLiteError(err))
+	}
+	return updated, nil
+}
+
+// selectBatch reads the tickets selected by a batch update, which the
+// immediate transaction keeps from changing. It returns them by ID together
+// with the order in which they should be processed, which for explicit ids
+// includes IDs that do not exist.
+func selectBatch(ctx context.Context, tx *sqliteTx, ids []string, filter ListFilter) (map[string]*Ticket, []string, error) {
+	var b queryBuilder
+	if len(ids) > 0 {
+		if len(ids) > MaxBatchSize {
+			return nil, nil, fmt.Errorf("%w: batch of %d tickets exceeds %d", ErrInvalidArgument, len(ids), MaxBatchSize)
+		}
+		b.add(inList("id", len(ids)), toArgs(ids)...)
+	}
+	b.addSQLiteFilter(filter)
+
+	b.args = append(b.args, MaxBatchSize+1)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM tickets
+		%s
+		ORDER BY id
+		LIMIT $%d`,
+		ticketColumns, b.where(), len(b.args))
+
+	rows, err := tx.QueryContext(ctx, query, sqliteArgs(b.args)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select tickets: %w", classifySQLiteError(err))
+	}
+	tickets, err := scanSQLiteTickets(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := make(map[string]*Ticket, len(tickets))
+	order := make([]string, 0, len(tickets))
+	for _, ticket := range tickets {
+		selected[ticket.ID] = ticket
+		order = append(order, ticket.ID)
+	}
+
+	if len(order) > MaxBatchSize {
+		return nil, nil, fmt.Errorf("%w: filter matches more than %d tickets", ErrInvalidArgument, MaxBatchSize)
+	}
+	if len(ids) > 0 {
+		order = ids
+	}
+
+	return selected, order, nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func scanSQLiteComment(row rowScanner) (*Comment, error) {
+	var comment Comment
+	var createdAt, editedAt sql.NullInt64
+	err := row.Scan(
+		&comment.ID,
+		&comment.TicketID,
+		&comment.AuthorID,
+		&comment.Body,
+		&createdAt,
+		&editedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comment.CreatedAt = fromMicros(createdAt).Time
+	comment.EditedAt = fromMicros(editedAt)
+	return &comment, nil
+}
+
+// SQLiteCommentRepository handles comment operations on a SQLite database.
+// Comments are removed together with their ticket by the ON DELETE CASCADE
+// foreign key.
+type SQLiteCommentRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCommentRepository creates a new SQLite comment repository
+func NewSQLiteCommentRepository(db *sql.DB) *SQLiteCommentRepository {
+	return &SQLiteCommentRepository{db: db}
+}
+
+// Create adds a comment to an existing ticket that is not in the trash
+func (r *SQLiteCommentRepository) Create(ctx context.Context, comment *Comment) (created *Comment, err error) {
+	ctx, span := startSQLiteSpan(ctx, "create_comment", "INSERT")
+	defer func() { endSpan(span, 1, err) }()
+
+	query := `
+		INSERT INTO comments (id, ticket_id, author_id, body, created_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE EXISTS (SELECT 1 FROM tickets WHERE id = $2 AND deleted_at IS NULL)
+		RETURNING ` + commentColumns
+
+	created, err = scanSQLiteComment(r.db.QueryRowContext(ctx, query,
+		comment.ID,
+		comment.TicketID,
+		comment.AuthorID,
+		comment.Body,
+		toMicros(comment.CreatedAt),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound(comment.TicketID)
+		}
+		return nil, fmt.Errorf("failed to create comment: %w", classifySQLiteError(err))
+	}
+
+	return created, nil
+}
+
+// GetByID retrieves a comment by ID
+func (r *SQLiteCommentRepository) GetByID(ctx context.Context, id string) (comment *Comment, err error) {
+	ctx, span := startSQLiteSpan(ctx, "get_comment", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
+
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1`
+
+	comment, err = scanSQLiteComment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, commentNotFound(id)
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", classifySQLiteError(err))
+	}
+
+	return comment, nil
+}
+
+// List retrieves the comments on a ticket oldest first, starting strictly
+// after the CreatedAt and ID of the given cursor
+func (r *SQLiteCommentRepository) List(ctx context.Context, ticketID string, limit int, after *ListCursor) (comments []*Comment, err error) {
+	ctx, span := startSQLiteSpan(ctx, "list_comments", "SELECT")
+	defer func() { endSpan(span, len(comments), err) }()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tickets WHERE id = $1 AND deleted_at IS NULL)`, ticketID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check ticket: %w", classifySQLiteError(err))
+	}
+	if !exists {
+		return nil, notFound(ticketID)
+	}
+
+	var b queryBuilder
+	b.add("ticket_id = ?", ticketID)
+	if after != nil {
+		b.add("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	b.args = append(b.args, limit)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM comments
+		%s
+		ORDER BY created_at, id
+		LIMIT $%d`,
+		commentColumns, b.where(), len(b.args))
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteArgs(b.args)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", classifySQLiteError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanSQLiteComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate comments: %w", classifySQLiteError(err))
+	}
+
+	return comments, nil
+}
+
+// Update replaces the body of a comment and stamps its edit time
+func (r *SQLiteCommentRepository) Update(ctx context.Context, id, body string) (comment *Comment, err error) {
+	ctx, span := startSQLiteSpan(ctx, "update_comment", "UPDATE")
+	defer func() { endSpan(span, 1, err) }()
+
+	query := `
+		UPDATE comments
+		SET body = $1, edited_at = $2
+		WHERE id = $3
+		RETURNING ` + commentColumns
+
+	comment, err = scanSQLiteComment(r.db.QueryRowContext(ctx, query, body, toMicros(time.Now()), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, commentNotFound(id)
+		}
+		return nil, fmt.Errorf("failed to update comment: %w", classifySQLiteError(err))
+	}
+
+	return comment, nil
+}
+
+// Delete deletes a comment by ID
+func (r *SQLiteCommentRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := startSQLiteSpan(ctx, "delete_comment", "DELETE")
+	defer func() { endSpan(span, 1, err) }()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", classifySQLiteError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return commentNotFound(id)
+	}
+
+	return nil
+}
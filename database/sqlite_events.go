@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// History returns the events recorded for a ticket, oldest first, starting
+// after the event with ID afterID. History outlives the ticket itself.
+func (r *SQLiteRepository) History(ctx context.Context, ticketID string, limit int, afterID int64) (events []*TicketEvent, err error) {
+	ctx, span := startSQLiteSpan(ctx, "get_ticket_history", "SELECT")
+	defer func() { endSpan(span, len(events), err) }()
+
+	query := `
+		SELECT id, ticket_id, event_type, actor, changes, ticket, created_at
+		FROM ticket_events
+		WHERE ticket_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, ticketID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket history: %w", classifySQLiteError(err))
+	}
+
+	return scanSQLiteEvents(rows)
+}
+
+// EventsAfter returns events across all tickets positioned after the given
+// position, oldest first. SQLite commits one transaction at a time, so event
+// IDs alone give the order.
+func (r *SQLiteRepository) EventsAfter(ctx context.Context, after EventPosition, limit int) (events []*TicketEvent, err error) {
+	ctx, span := startSQLiteSpan(ctx, "list_ticket_events", "SELECT")
+	defer func() { endSpan(span, len(events), err) }()
+
+	query := `
+		SELECT id, ticket_id, event_type, actor, changes, ticket, created_at
+		FROM ticket_events
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ticket events: %w", classifySQLiteError(err))
+	}
+
+	return scanSQLiteEvents(rows)
+}
+
+// EventHorizon returns the position of the last committed event. A writer
+// in flight holds the database lock, so its events will come after it.
+func (r *SQLiteRepository) EventHorizon(ctx context.Context) (position EventPosition, err error) {
+	ctx, span := startSQLiteSpan(ctx, "get_event_horizon", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
+
+	// The sequence rather than MAX(id), which drops when the latest events are purged
+	query := `SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'ticket_events'), 0)`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&position.ID); err != nil {
+		return EventPosition{}, fmt.Errorf("failed to get event horizon: %w", classifySQLiteError(err))
+	}
+
+	return position, nil
+}
+
+// EventByID retrieves a single ticket event
+func (r *SQLiteRepository) EventByID(ctx context.Context, id int64) (event *TicketEvent, err error) {
+	ctx, span := startSQLiteSpan(ctx, "get_ticket_event", "SELECT")
+	defer func() { endSpan(span, 1, err) }()
+
+	query := `
+		SELECT id, ticket_id, event_type, actor, changes, ticket, created_at
+		FROM ticket_events
+		WHERE id = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket event: %w", classifySQLiteError(err))
+	}
+
+	events, err := scanSQLiteEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: event %d", ErrNotFound, id)
+	}
+
+	return events[0], nil
+}
+
+// scanSQLiteEvents reads and closes rows of ticket_events
+func scanSQLiteEvents(rows *sql.Rows) ([]*TicketEvent, error) {
+	defer rows.Close()
+
+	var events []*TicketEvent
+	for rows.Next() {
+		var event TicketEvent
+		var changesJSON string
+		var ticketJSON sql.NullString
+		var createdAt sql.NullInt64
+		if err := rows.Scan(&event.ID, &event.TicketID, &event.Type, &event.Actor, &changesJSON, &ticketJSON, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket event: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changesJSON), &event.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal changes from JSON: %w", err)
+		}
+		ticket, err := unmarshalEventTicket(ticketJSON)
+		if err != nil {
+			return nil, err
+		}
+		event.Ticket = ticket
+		event.CreatedAt = fromMicros(createdAt).Time
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ticket events: %w", classifySQLiteError(err))
+	}
+
+	return events, nil
+}
+
+// recordSQLiteEvents appends events to the ticket history within tx using a
+// single multi-row INSERT, and remembers their IDs to announce on commit.
+// Every event is attributed to the actor of ctx.
+func recordSQLiteEvents(ctx context.Context, tx *sqliteTx, events []pendingEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	actor := ActorFromContext(ctx)
+	values := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*5)
+
+	for _, event := range events {
+		changes := event.changes
+		if changes == nil {
+			changes = []FieldChange{}
+		}
+
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal changes to JSON: %w", err)
+		}
+		ticketJSON, err := marshalEventTicket(event.ticket)
+		if err != nil {
+			return err
+		}
+
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, event.ticketID, event.eventType, actor, string(changesJSON), ticketJSON)
+	}
+
+	query := `INSERT INTO ticket_events (ticket_id, event_type, actor, changes, ticket) VALUES ` + strings.Join(values, ", ") + ` RETURNING id`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to record ticket event: %w", classifySQLiteError(err))
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan ticket event id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to record ticket event: %w", classifySQLiteError(err))
+	}
+
+	// RETURNING does not promise any order
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	tx.events = append(tx.events, ids...)
+
+	return nil
+}
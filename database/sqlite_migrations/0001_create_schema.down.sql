@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS ticket_events;
+DROP TRIGGER IF EXISTS tickets_fts_update;
+DROP TRIGGER IF EXISTS tickets_fts_delete;
+DROP TRIGGER IF EXISTS tickets_fts_insert;
+DROP TABLE IF EXISTS tickets_fts;
+DROP TABLE IF EXISTS tickets;
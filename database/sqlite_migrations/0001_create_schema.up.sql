@@ -0,0 +1,75 @@
+-- Initial schema of the SQLite backend, equivalent to the PostgreSQL
+-- migrations of the time. Timestamps are microseconds since the Unix epoch
+-- and tags a JSON array. Check constraints are named after the column they
+-- check. IF NOT EXISTS adopts files created before SQLite had migrations.
+CREATE TABLE IF NOT EXISTS tickets (
+    -- Stable rowid for the full-text index, which VACUUM could renumber otherwise
+    seq INTEGER PRIMARY KEY,
+    id TEXT NOT NULL UNIQUE CONSTRAINT id CHECK (length(id) <= 255),
+    title TEXT NOT NULL CONSTRAINT title CHECK (length(title) <= 500),
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'OPEN' CONSTRAINT status CHECK (length(status) <= 50),
+    priority TEXT NOT NULL DEFAULT 'MEDIUM' CONSTRAINT priority CHECK (length(priority) <= 50),
+    assignee_id TEXT CONSTRAINT assignee_id CHECK (length(assignee_id) <= 255),
+    tags TEXT DEFAULT '[]' CONSTRAINT tags CHECK (json_valid(tags)),
+    created_at INTEGER DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    updated_at INTEGER DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    reporter_id TEXT NOT NULL CONSTRAINT reporter_id CHECK (length(reporter_id) <= 255),
+    version INTEGER NOT NULL DEFAULT 1,
+    resolution_note TEXT,
+    resolved_at INTEGER,
+    closed_at INTEGER,
+    deleted_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_tickets_status ON tickets(status);
+CREATE INDEX IF NOT EXISTS idx_tickets_priority ON tickets(priority);
+CREATE INDEX IF NOT EXISTS idx_tickets_assignee_id ON tickets(assignee_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_reporter_id ON tickets(reporter_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_created_at_id ON tickets(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_tickets_updated_at_id ON tickets(updated_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_tickets_deleted_at_id ON tickets(deleted_at DESC, id DESC) WHERE deleted_at IS NOT NULL;
+
+-- Full-text index over title and description, kept in sync by triggers
+CREATE VIRTUAL TABLE IF NOT EXISTS tickets_fts USING fts5(
+    title, description,
+    content = 'tickets', content_rowid = 'seq',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS tickets_fts_insert AFTER INSERT ON tickets BEGIN
+    INSERT INTO tickets_fts (rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tickets_fts_delete AFTER DELETE ON tickets BEGIN
+    INSERT INTO tickets_fts (tickets_fts, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tickets_fts_update AFTER UPDATE OF title, description ON tickets BEGIN
+    INSERT INTO tickets_fts (tickets_fts, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+    INSERT INTO tickets_fts (rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+
+-- Change history; AUTOINCREMENT keeps event IDs from being reused after a purge
+CREATE TABLE IF NOT EXISTS ticket_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    changes TEXT NOT NULL DEFAULT '[]',
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_events_ticket_id ON ticket_events(ticket_id, id);
+
+-- Deleted with their ticket; requires PRAGMA foreign_keys, set on every connection
+CREATE TABLE IF NOT EXISTS comments (
+    id TEXT PRIMARY KEY CONSTRAINT id CHECK (length(id) <= 255),
+    ticket_id TEXT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    author_id TEXT NOT NULL CONSTRAINT author_id CHECK (length(author_id) <= 255),
+    body TEXT NOT NULL,
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    edited_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_ticket_id ON comments(ticket_id, created_at, id);
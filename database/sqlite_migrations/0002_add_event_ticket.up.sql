@@ -0,0 +1,4 @@
+-- The JSON state of the ticket after each event, so that change feeds can
+-- replay events as they happened. NULL once the ticket is deleted, and for
+-- events recorded before this migration.
+ALTER TABLE ticket_events ADD COLUMN ticket TEXT;
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ftsQuery translates a web search into an FTS5 query. FTS5 cannot match on
+// exclusions alone, so alternatives made only of them are left out.
+func ftsQuery(q searchQuery) string {
+	var alternatives []string
+	for _, group := range q {
+		var include, exclude []string
+		for _, clause := range group {
+			phrase := `"` + strings.Join(clause.words, " ") + `"`
+			if clause.negated {
+				exclude = append(exclude, phrase)
+			} else {
+				include = append(include, phrase)
+			}
+		}
+		if len(include) == 0 {
+			continue
+		}
+
+		expr := "(" + strings.Join(include, " AND ") + ")"
+		for _, phrase := range exclude {
+			expr += " NOT " + phrase
+		}
+		alternatives = append(alternatives, "("+expr+")")
+	}
+	return strings.Join(alternatives, " OR ")
+}
+
+// qualifiedTicketColumns is ticketColumns prefixed with the tickets table,
+// for queries joining tables with columns of the same name
+var qualifiedTicketColumns = func() string {
+	columns := strings.Split(ticketColumns, ",")
+	for i, column := range columns {
+		columns[i] = "tickets." + strings.TrimSpace(column)
+	}
+	return strings.Join(columns, ", ")
+}()
+
+// Search ranks tickets by relevance of their title and description to free
+// text using the FTS5 index and its BM25 ranking, with title matches weighed
+// above description matches like the PostgreSQL search. Results are ordered
+// by rank, then ID, and paged with the Rank and ID of the cursor.
+func (r *SQLiteRepository) Search(ctx context.Context, text string, filter ListFilter, limit int, after *ListCursor) (results []*SearchResult, err error) {
+	ctx, span := startSQLiteSpan(ctx, "search_tickets", "SELECT")
+	defer func() { endSpan(span, len(results), err) }()
+
+	match := ftsQuery(parseSearch(text))
+	if match == "" {
+		return nil, nil // only stop words, which match nothing
+	}
+
+	var b queryBuilder
+	// $1 is the match, referenced again to build the snippets
+	b.add("tickets_fts MATCH ?", match)
+	b.addSQLiteFilter(filter)
+
+	// bm25 is lower for better matches
+	rankExpr := fmt.Sprintf("-bm25(tickets_fts, %g, %g)", titleWeight, descriptionWeight)
+	if after != nil {
+		b.add(fmt.Sprintf("(%s, tickets.id) < (?, ?)", rankExpr), after.Rank, after.ID)
+	}
+
+	// Snippets are only built for the page, once it has been selected. char(2)
+	// and char(3) are snippetStart and snippetStop.
+	b.args = append(b.args, limit)
+	query := fmt.Sprintf(`
+		SELECT %s,
+			page.relevance,
+			coalesce(snippet(tickets_fts, 0, char(2), char(3), '', %d), ''),
+			coalesce(snippet(tickets_fts, 1, char(2), char(3), '', %d), '')
+		FROM (
+			SELECT tickets_fts.rowid AS seq, %s AS relevance, tickets.id AS id
+			FROM tickets_fts
+			JOIN tickets ON tickets.seq = tickets_fts.rowid
+			%s
+			ORDER BY relevance DESC, tickets.id DESC
+			LIMIT $%d
+		) AS page
+		JOIN tickets ON tickets.seq = page.seq
+		JOIN tickets_fts ON tickets_fts.rowid = page.seq
+		WHERE tickets_fts MATCH $1
+		ORDER BY page.relevance DESC, page.id DESC`,
+		qualifiedTicketColumns, headlineMaxWords, headlineMaxWords, rankExpr, b.where(), len(b.args))
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteArgs(b.args)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tickets: %w", classifySQLiteError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result SearchResult
+		ticket, err := scanSQLiteTicket(rows, &result.Rank, &result.TitleSnippet, &result.DescriptionSnippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		result.Ticket = ticket
+		result.TitleSnippet = renderSnippet(result.TitleSnippet)
+		result.DescriptionSnippet = renderSnippet(result.DescriptionSnippet)
+		results = append(results, &result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", classifySQLiteError(err))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// TicketCount is the number of tickets with one status and priority
+type TicketCount struct {
+	Status   string
+	Priority string
+	Count    int64
+}
+
+// CountByStatusAndPriority counts the tickets that are not in the trash
+func (r *TicketRepository) CountByStatusAndPriority(ctx context.Context) (counts []TicketCount, err error) {
+	ctx, span := startSpan(ctx, "count_tickets", "SELECT")
+	defer func() { endSpan(span, len(counts), err) }()
+
+	query := `
+		SELECT status, priority, COUNT(*)
+		FROM tickets
+		WHERE deleted_at IS NULL
+		GROUP BY status, priority`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tickets: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c TicketCount
+		if err := rows.Scan(&c.Status, &c.Priority, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ticket counts: %w", classifyError(err))
+	}
+
+	return counts, nil
+}
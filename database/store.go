@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// timeNow stamps ticket updates and deletions. Tests replace it to give
+// several tickets the same timestamp.
+var timeNow = time.Now
+
+// TicketStore stores tickets and their history. TicketRepository implements
+// it on PostgreSQL, SQLiteRepository on SQLite and MemoryStore in memory, with
+// the same ordering, errors and tag handling.
+type TicketStore interface {
+	Create(ctx context.Context, ticket *Ticket) (*Ticket, error)
+	CreateBatch(ctx context.Context, tickets []*Ticket) ([]*Ticket, error)
+	GetByID(ctx context.Context, id string) (*Ticket, error)
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*Ticket, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*Ticket, error)
+	List(ctx context.Context, q ListQuery, limit int, after *ListCursor) ([]*Ticket, error)
+	Search(ctx context.Context, text string, filter ListFilter, limit int, after *ListCursor) ([]*SearchResult, error)
+	Update(ctx context.Context, id string, updates map[string]interface{}, expectedVersion int64) (*Ticket, error)
+	UpdateBatch(ctx context.Context, ids []string, filter ListFilter, atomic bool, fn BatchUpdateFunc) ([]BatchResult, error)
+	Delete(ctx context.Context, id string, expectedVersion int64) error
+	Restore(ctx context.Context, id string, expectedVersion int64) (*Ticket, error)
+	PurgeDeleted(ctx context.Context, olderThan time.Time, limit int) (int, error)
+	History(ctx context.Context, ticketID string, limit int, afterID int64) ([]*TicketEvent, error)
+	EventsAfter(ctx context.Context, after EventPosition, limit int) ([]*TicketEvent, error)
+	EventHorizon(ctx context.Context) (EventPosition, error)
+	EventByID(ctx context.Context, id int64) (*TicketEvent, error)
+	CountByStatusAndPriority(ctx context.Context) ([]TicketCount, error)
+}
+
+// CommentStore stores the comments on tickets
+type CommentStore interface {
+	Create(ctx context.Context, comment *Comment) (*Comment, error)
+	GetByID(ctx context.Context, id string) (*Comment, error)
+	List(ctx context.Context, ticketID string, limit int, after *ListCursor) ([]*Comment, error)
+	Update(ctx context.Context, id, body string) (*Comment, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// EventSource announces that ticket events have been committed. Every call
+// of the fn given to Run means "re-read the feed": it carries no event, and
+// readers fetch what is new with EventsAfter. EventListener implements it
+// with LISTEN/NOTIFY, LocalEventListener in process.
+type EventSource interface {
+	Run(ctx context.Context, fn func())
+	Close() error
+}
+
+var (
+	_ TicketStore  = (*TicketRepository)(nil)
+	_ CommentStore = (*CommentRepository)(nil)
+	_ EventSource  = (*EventListener)(nil)
+
+	_ TicketStore  = (*MemoryStore)(nil)
+	_ CommentStore = memoryComments{}
+	_ EventSource  = (*LocalEventListener)(nil)
+
+	_ TicketStore  = (*SQLiteRepository)(nil)
+	_ CommentStore = (*SQLiteCommentRepository)(nil)
+)
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testTicketStore(t, func(t *testing.T) TicketStore {
+		return NewMemoryStore()
+	})
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	// A file rather than :memory:, so that concurrent writers really
+	// contend for the database lock
+	testTicketStore(t, func(t *testing.T) TicketStore {
+		db, err := NewConnection(Config{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "tickets.db")})
+		if err != nil {
+			t.Fatalf("failed to open SQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return NewSQLiteRepository(db)
+	})
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of repository queries
+var tracer = otel.Tracer("gRPC/database")
+
+// startSpan starts a span for one repository statement as a child of the
+// span in ctx. Calls without a parent span, such as metrics scrapes and the
+// change feed poller, are not traced so they do not each start a new trace.
+func startSpan(ctx context.Context, statement, operation string) (context.Context, trace.Span) {
+	return startDBSpan(ctx, "postgresql", statement, operation)
+}
+
+// startSQLiteSpan is startSpan for statements of the SQLite backend
+func startSQLiteSpan(ctx context.Context, statement, operation string) (context.Context, trace.Span) {
+	return startDBSpan(ctx, "sqlite", statement, operation)
+}
+
+func startDBSpan(ctx context.Context, system, statement, operation string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return tracer.Start(ctx, statement,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.statement.name", statement),
+		))
+}
+
+// endSpan records the number of rows the statement returned or changed, or
+// its error, and ends the span
+func endSpan(span trace.Span, rows int, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("db.response.returned_rows", rows))
+	}
+	span.End()
+}
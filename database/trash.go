@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Restore takes a ticket out of the trash and records it in the ticket
+// history. A non-zero expectedVersion makes the restore fail with
+// ErrVersionConflict unless the ticket is still at that version.
+func (r *TicketRepository) Restore(ctx context.Context, id string, expectedVersion int64) (restored *Ticket, err error) {
+	ctx, span := startSpan(ctx, "restore_ticket", "UPDATE")
+	defer func() { endSpan(span, 1, err) }()
+
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		query := `SELECT ` + ticketColumns + ` FROM tickets WHERE id = $1 FOR UPDATE`
+
+		before, err := scanTicket(tx.QueryRowContext(ctx, query, id))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return notFound(id)
+			}
+			return fmt.Errorf("failed to get ticket: %w", classifyError(err))
+		}
+		if !before.DeletedAt.Valid {
+			return &ConstraintError{Kind: ErrFailedPrecondition, Constraint: "NOT_DELETED", Err: fmt.Errorf("ticket %s is not deleted", id)}
+		}
+		if expectedVersion != 0 && before.Version != expectedVersion {
+			return versionConflict(id, before.Version, expectedVersion)
+		}
+
+		query = `
+			UPDATE tickets
+			SET deleted_at = NULL, version = version + 1
+			WHERE id = $1
+			RETURNING ` + ticketColumns
+
+		restored, err = scanTicket(tx.QueryRowContext(ctx, query, id))
+		if err != nil {
+			return fmt.Errorf("failed to restore ticket: %w", classifyError(err))
+		}
+
+		// Recorded like a creation, so readers of the history see the ticket reappear
+		return recordEvent(ctx, tx, id, EventRestored, diffTickets(nil, restored), restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// PurgeDeleted permanently removes up to limit tickets that were moved to the
+// trash before olderThan, together with their comments, and returns how many
+// were removed. Their history is kept and ends with a purge event. Rows locked
+// by another transaction are skipped, so several purgers may run at once.
+func (r *TicketRepository) PurgeDeleted(ctx context.Context, olderThan time.Time, limit int) (purged int, err error) {
+	ctx, span := startSpan(ctx, "purge_tickets", "DELETE")
+	defer func() { endSpan(span, purged, err) }()
+
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+			DELETE FROM tickets
+			WHERE id IN (
+				SELECT id FROM tickets
+				WHERE deleted_at < $1
+				ORDER BY deleted_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id`
+
+		rows, err := tx.QueryContext(ctx, query, olderThan, limit)
+		if err != nil {
+			return fmt.Errorf("failed to purge tickets: %w", classifyError(err))
+		}
+		defer rows.Close()
+
+		var events []pendingEvent
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan ticket id: %w", err)
+			}
+			events = append(events, pendingEvent{ticketID: id, eventType: EventPurged})
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to purge tickets: %w", classifyError(err))
+		}
+
+		purged = len(events)
+		return recordEvents(ctx, tx, events)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
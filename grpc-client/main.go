@@ -4,18 +4,61 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	ticketpb "gRPC/proto/ticket"
+	"gRPC/tlsconfig"
+	"gRPC/tracing"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
+// transportCredentials uses TLS when a CA bundle or client certificate is
+// configured, presenting the client certificate for mutual TLS, and falls
+// back to plaintext otherwise. The certificates are reloaded until stop is
+// called; share the credentials between connections rather than calling
+// this again.
+func transportCredentials() (creds credentials.TransportCredentials, stop func()) {
+	caFile, certFile := os.Getenv("TLS_CA_FILE"), os.Getenv("TLS_CERT_FILE")
+	if caFile == "" && certFile == "" {
+		return insecure.NewCredentials(), func() {}
+	}
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.Files{
+		CertFile: certFile,
+		KeyFile:  os.Getenv("TLS_KEY_FILE"),
+		CAFile:   caFile,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificates: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go reloader.Watch(ctx, 10*time.Second)
+
+	return credentials.NewTLS(reloader.ClientConfig(os.Getenv("TLS_SERVER_NAME"))), cancel
+}
+
 func main() {
+	// Trace context is sent with every call so server spans join the client's trace
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.FromEnv("ticket-client"))
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	creds, stopReloading := transportCredentials()
+	defer stopReloading()
+
 	// Connect to gRPC server
 	conn, err := grpc.NewClient("grpc-server:50051",
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
@@ -26,14 +69,24 @@ func main() {
 
 	log.Println("🚀 Connected to Ticket gRPC Service")
 
+	// Group the example calls under one trace
+	ctx, span := otel.Tracer("gRPC/grpc-client").Start(context.Background(), "client_examples")
+	defer span.End()
+
+	// Authenticate every call with the bearer token, if one is configured
+	if token := os.Getenv("AUTH_TOKEN"); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
 	// Example 1: Create a ticket
 	fmt.Println("\n=== Creating a new ticket ===")
-	createResp, err := client.CreateTicket(context.Background(), &ticketpb.CreateTicketRequest{
+	createResp, err := client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{
 		Title:       "Fix authentication bug",
 		Description: "Users can't login with Google OAuth",
 		Priority:    ticketpb.TicketPriority_TICKET_PRIORITY_HIGH,
 		AssigneeId:  "user-123",
 		Tags:        []string{"bug", "authentication", "urgent"},
+		ReporterId:  "user-789",
 	})
 	if err != nil {
 		log.Printf("Failed to create ticket: %v", err)
@@ -43,7 +96,7 @@ func main() {
 
 		// Example 2: Get the ticket we just created
 		fmt.Println("\n=== Getting the ticket ===")
-		getResp, err := client.GetTicket(context.Background(), &ticketpb.GetTicketRequest{
+		getResp, err := client.GetTicket(ctx, &ticketpb.GetTicketRequest{
 			Id: ticketID,
 		})
 		if err != nil {
@@ -62,7 +115,7 @@ func main() {
 
 		// Example 3: Update the ticket
 		fmt.Println("\n=== Updating the ticket ===")
-		updateResp, err := client.UpdateTicket(context.Background(), &ticketpb.UpdateTicketRequest{
+		updateResp, err := client.UpdateTicket(ctx, &ticketpb.UpdateTicketRequest{
 			Id:     ticketID,
 			Status: ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS,
 			Title:  "Fix authentication bug - URGENT",
@@ -77,7 +130,7 @@ func main() {
 
 	// Example 4: List all tickets
 	fmt.Println("\n=== Listing all tickets ===")
-	listResp, err := client.ListTickets(context.Background(), &ticketpb.ListTicketsRequest{
+	listResp, err := client.ListTickets(ctx, &ticketpb.ListTicketsRequest{
 		PageSize: 10,
 	})
 	if err != nil {
@@ -92,12 +145,13 @@ func main() {
 
 	// Example 5: Create another ticket
 	fmt.Println("\n=== Creating another ticket ===")
-	_, err = client.CreateTicket(context.Background(), &ticketpb.CreateTicketRequest{
+	_, err = client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{
 		Title:       "Add dark mode",
 		Description: "Users requested dark mode for better UX",
 		Priority:    ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM,
 		AssigneeId:  "user-456",
 		Tags:        []string{"feature", "ui", "enhancement"},
+		ReporterId:  "user-789",
 	})
 	if err != nil {
 		log.Printf("Failed to create second ticket: %v", err)
@@ -109,12 +163,13 @@ func main() {
 }
 
 // Advanced example: Client with timeout and error handling
-func createClientWithTimeout() {
+func createClientWithTimeout(creds credentials.TransportCredentials) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	conn, err := grpc.NewClient("localhost:50051",
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
@@ -124,7 +179,8 @@ func createClientWithTimeout() {
 
 	// Create ticket with timeout
 	resp, err := client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{
-		Title: "Timeout test ticket",
+		Title:      "Timeout test ticket",
+		ReporterId: "user-789",
 	})
 	if err != nil {
 		log.Printf("Error with timeout: %v", err)
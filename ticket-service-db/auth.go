@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"log"
+	"strings"
+
+	"gRPC/auth"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/ticketservice"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationMetadataKey carries the caller's "Bearer <jwt>" credentials
+const authorizationMetadataKey = "authorization"
+
+// authenticator rejects calls without valid credentials and stores the
+// caller's identity in the context of the others. Callers present either a
+// bearer token or, over mutual TLS, a client certificate.
+type authenticator struct {
+	verifier *auth.Verifier // nil when bearer tokens are not accepted
+}
+
+func newAuthenticator(verifier *auth.Verifier) *authenticator {
+	return &authenticator{verifier: verifier}
+}
+
+// authenticate verifies the bearer token in the request metadata, falling
+// back to the verified client certificate of the connection
+func (a *authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		if cert := peerCertificate(ctx); cert != nil {
+			id, err := auth.FromCertificate(cert)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			return auth.WithIdentity(ctx, id), nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token or client certificate")
+	}
+	if a.verifier == nil {
+		return nil, status.Error(codes.Unauthenticated, "bearer tokens are not accepted; use a client certificate")
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, status.Error(codes.Unauthenticated, `authorization must be "Bearer <token>"`)
+	}
+
+	id, err := a.verifier.Verify(token)
+	if err != nil {
+		log.Printf("gRPC: Rejected bearer token: %v", err)
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+
+	return auth.WithIdentity(ctx, id), nil
+}
+
+// peerCertificate returns the client certificate of a mutual TLS connection,
+// if the TLS handshake verified one
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// unaryInterceptor authenticates unary calls other than health checks
+func (a *authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if isHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applyIdentity(ctx, req)
+	return handler(ctx, req)
+}
+
+// streamInterceptor authenticates streaming calls once, when the stream
+// opens, except health watches
+func (a *authenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isHealthMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &identityStream{ticketservice.StreamWithContext(ss, ctx)})
+}
+
+// identityStream applies the caller's identity to every message received
+type identityStream struct {
+	grpc.ServerStream
+}
+
+func (s *identityStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	applyIdentity(s.Context(), m)
+	return nil
+}
+
+// applyIdentity overwrites request fields that name the caller with the
+// authenticated identity, so that they cannot be spoofed
+func applyIdentity(ctx context.Context, req interface{}) {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	switch r := req.(type) {
+	case *ticketpb.CreateTicketRequest:
+		r.ReporterId = id.Subject
+	case *ticketpb.AddCommentRequest:
+		r.AuthorId = id.Subject
+	}
+}
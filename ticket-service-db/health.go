@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	ticketpb "gRPC/proto/ticket"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// healthCheckInterval is how often the database is pinged to update the health status
+	healthCheckInterval = 5 * time.Second
+	// healthCheckTimeout bounds a single ping, and a probe by the healthcheck command
+	healthCheckTimeout = 3 * time.Second
+)
+
+// healthCheckedServices are reported together: the server as a whole ("")
+// and the ticket service both depend on the database
+var healthCheckedServices = []string{"", ticketpb.TicketService_ServiceDesc.ServiceName}
+
+// isHealthMethod reports whether fullMethod belongs to the health service,
+// which orchestrators and load balancers call without credentials
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// newHealthServer creates a health service that reports NOT_SERVING until
+// the first successful database ping
+func newHealthServer() *health.Server {
+	hs := health.NewServer()
+	for _, service := range healthCheckedServices {
+		hs.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return hs
+}
+
+// runHealthChecks pings the database every interval until ctx is cancelled and
+// reports the services as SERVING only while the ping succeeds
+func runHealthChecks(ctx context.Context, db *sql.DB, hs *health.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	current := healthpb.HealthCheckResponse_NOT_SERVING
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		next := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			next = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if next != current {
+			if err != nil {
+				log.Printf("gRPC: Database ping failed, reporting %s: %v", next, err)
+			} else {
+				log.Printf("💚 Database reachable, reporting %s", next)
+			}
+			current = next
+		}
+		for _, service := range healthCheckedServices {
+			hs.SetServingStatus(service, next)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeHealth checks a server listening on localhost, configured by the same
+// environment as the probe, and returns the process exit code: 0 when it is
+// serving, 1 otherwise. It backs the healthcheck command used by container
+// health checks.
+func probeHealth(port string) int {
+	creds, err := probeCredentials()
+	if err != nil {
+		fmt.Printf("failed to set up TLS: %v\n", err)
+		return 1
+	}
+
+	conn, err := grpc.NewClient("localhost:"+port, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		fmt.Printf("failed to connect: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		fmt.Printf("health check failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(resp.Status)
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return 1
+	}
+	return 0
+}
+
+// probeCredentials connects over plaintext unless TLS_CERT_FILE is set. With
+// TLS the server must present that very certificate, which needs neither a CA
+// nor a host name matching localhost. When client certificates are required,
+// the probe presents TLS_HEALTHCHECK_CERT_FILE and TLS_HEALTHCHECK_KEY_FILE.
+func probeCredentials() (credentials.TransportCredentials, error) {
+	certFile := getEnv("TLS_CERT_FILE", "")
+	if certFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	server, err := readCertificate(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Replaced by the comparison with the server's own certificate
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 || !bytes.Equal(cs.PeerCertificates[0].Raw, server) {
+				return fmt.Errorf("server did not present the certificate in %s", certFile)
+			}
+			return nil
+		},
+	}
+
+	clientAuth, err := parseClientAuth(getEnv("TLS_CLIENT_AUTH", ""), getEnv("TLS_CLIENT_CA_FILE", "") != "")
+	if err != nil {
+		return nil, err
+	}
+	if clientAuth == tls.RequireAndVerifyClientCert {
+		probeCertFile := getEnv("TLS_HEALTHCHECK_CERT_FILE", "")
+		if probeCertFile == "" {
+			return nil, errors.New("TLS_HEALTHCHECK_CERT_FILE must be set when client certificates are required")
+		}
+		cert, err := tls.LoadX509KeyPair(probeCertFile, getEnv("TLS_HEALTHCHECK_KEY_FILE", ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load healthcheck certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// readCertificate returns the first certificate of a PEM file in DER form
+func readCertificate(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found in %s", path)
+		}
+		if block.Type == "CERTIFICATE" {
+			return block.Bytes, nil
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testCA issues certificates for the tests and writes them to dir
+type testCA struct {
+	dir    string
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	t.Helper()
+	ca := &testCA{dir: dir}
+	ca.cert, ca.key = ca.issue(t, "ca", &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	return ca
+}
+
+// issue signs template with the CA, or itself for the CA, and writes the
+// certificate and key to name.crt and name.key
+func (ca *testCA) issue(t *testing.T, name string, template *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	ca.serial++
+	template.SerialNumber = big.NewInt(ca.serial)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if ca.cert != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	writePEM(t, ca.path(name+".crt"), "CERTIFICATE", der)
+	writePEM(t, ca.path(name+".key"), "EC PRIVATE KEY", keyDER)
+
+	return cert, key
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// serveHealth starts a server configured by loadServerTLS, like the real
+// one, that reports SERVING, and returns its port
+func serveHealth(t *testing.T) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	serverTLS, _, err := loadServerTLS(ctx)
+	if err != nil {
+		t.Fatalf("failed to set up TLS: %v", err)
+	}
+
+	hs := newHealthServer()
+	for _, service := range healthCheckedServices {
+		hs.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLS)))
+	healthpb.RegisterHealthServer(s, hs)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port
+}
+
+func TestProbeHealthOverTLS(t *testing.T) {
+	ca := newTestCA(t, t.TempDir())
+	// Named for the service rather than localhost, as in a container
+	ca.issue(t, "server", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "grpc-server"},
+		DNSNames:    []string{"grpc-server"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	ca.issue(t, "healthcheck", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "healthcheck"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	ca.issue(t, "other", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "grpc-server"},
+		DNSNames:    []string{"grpc-server"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want int
+	}{
+		{
+			name: "TLS",
+			want: 0,
+		},
+		{
+			name: "mutual TLS",
+			env: map[string]string{
+				"TLS_CLIENT_CA_FILE":        ca.path("ca.crt"),
+				"TLS_HEALTHCHECK_CERT_FILE": ca.path("healthcheck.crt"),
+				"TLS_HEALTHCHECK_KEY_FILE":  ca.path("healthcheck.key"),
+			},
+			want: 0,
+		},
+		{
+			name: "mutual TLS without a healthcheck certificate",
+			env:  map[string]string{"TLS_CLIENT_CA_FILE": ca.path("ca.crt")},
+			want: 1,
+		},
+		{
+			name: "optional client certificates",
+			env: map[string]string{
+				"TLS_CLIENT_CA_FILE": ca.path("ca.crt"),
+				"TLS_CLIENT_AUTH":    "optional",
+			},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TLS_CERT_FILE", ca.path("server.crt"))
+			t.Setenv("TLS_KEY_FILE", ca.path("server.key"))
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			port := serveHealth(t)
+
+			if got := probeHealth(port); got != tt.want {
+				t.Errorf("probeHealth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("different certificate", func(t *testing.T) {
+		t.Setenv("TLS_CERT_FILE", ca.path("server.crt"))
+		t.Setenv("TLS_KEY_FILE", ca.path("server.key"))
+		port := serveHealth(t)
+
+		// The probe expects the server to present the certificate it is configured with
+		t.Setenv("TLS_CERT_FILE", ca.path("other.crt"))
+		if got := probeHealth(port); got != 1 {
+			t.Errorf("probeHealth() = %d, want 1", got)
+		}
+	})
+}
+
+func TestProbeCredentialsPlaintext(t *testing.T) {
+	t.Setenv("TLS_CERT_FILE", "")
+	creds, err := probeCredentials()
+	if err != nil {
+		t.Fatalf("probeCredentials() failed: %v", err)
+	}
+	if got := creds.Info().SecurityProtocol; got != "insecure" {
+		t.Errorf("security protocol = %q, want insecure", got)
+	}
+}
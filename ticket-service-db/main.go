@@ -2,310 +2,268 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"gRPC/auth"
 	"gRPC/database"
 	ticketpb "gRPC/proto/ticket"
+	"gRPC/ticketservice"
+	"gRPC/tracing"
 
-	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
-// ticketServer implements the TicketService gRPC service with PostgreSQL
-type ticketServer struct {
-	ticketpb.UnimplementedTicketServiceServer
-	repo *database.TicketRepository
-}
-
-// newTicketServer creates a new ticket server with database repository
-func newTicketServer(db *sql.DB) *ticketServer {
-	return &ticketServer{
-		repo: database.NewTicketRepository(db),
+// getEnv gets environment variable with fallback
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
 
-// Helper functions to convert between protobuf and database models
-func dbTicketToProto(dbTicket *database.Ticket) *ticketpb.Ticket {
-	ticket := &ticketpb.Ticket{
-		Id:        dbTicket.ID,
-		Title:     dbTicket.Title,
-		Status:    convertStatusToProto(dbTicket.Status),
-		Priority:  convertPriorityToProto(dbTicket.Priority),
-		Tags:      dbTicket.Tags,
-		CreatedAt: timestamppb.New(dbTicket.CreatedAt),
-		UpdatedAt: timestamppb.New(dbTicket.UpdatedAt),
+// dbConfigFromEnv reads the database configuration from environment variables
+func dbConfigFromEnv() database.Config {
+	return database.Config{
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     getEnv("DB_PORT", "5432"),
+		User:     getEnv("DB_USER", "ayushpandya"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		DBName:   getEnv("DB_NAME", "ticketdb"),
+		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+		Path:     getEnv("SQLITE_PATH", "tickets.db"),
 	}
+}
 
-	if dbTicket.Description.Valid {
-		ticket.Description = dbTicket.Description.String
+// parseBulkBatchSize reads BULK_BATCH_SIZE, which must fit in a single repository batch
+func parseBulkBatchSize(value string) (int, error) {
+	if value == "" {
+		return ticketservice.DefaultBulkBatchSize, nil
 	}
 
-	if dbTicket.AssigneeID.Valid {
-		ticket.AssigneeId = dbTicket.AssigneeID.String
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 || size > database.MaxBatchSize {
+		return 0, fmt.Errorf("BULK_BATCH_SIZE must be between 1 and %d, got %q", database.MaxBatchSize, value)
 	}
 
-	return ticket
+	return size, nil
 }
 
-func convertStatusToProto(status string) ticketpb.TicketStatus {
-	switch status {
-	case "OPEN":
-		return ticketpb.TicketStatus_TICKET_STATUS_OPEN
-	case "IN_PROGRESS":
-		return ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS
-	case "RESOLVED":
-		return ticketpb.TicketStatus_TICKET_STATUS_RESOLVED
-	case "CLOSED":
-		return ticketpb.TicketStatus_TICKET_STATUS_CLOSED
-	default:
-		return ticketpb.TicketStatus_TICKET_STATUS_OPEN
+func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "healthcheck":
+			// Probes a running server, for container health checks
+			os.Exit(probeHealth(getEnv("GRPC_PORT", "50051")))
+		case "migrate":
+			os.Exit(runMigrateCommand(os.Args[2:]))
+		default:
+			log.Fatalf("Unknown command %q, expected healthcheck or migrate", os.Args[1])
+		}
 	}
-}
 
-func convertPriorityToProto(priority string) ticketpb.TicketPriority {
-	switch priority {
-	case "LOW":
-		return ticketpb.TicketPriority_TICKET_PRIORITY_LOW
-	case "MEDIUM":
-		return ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM
-	case "HIGH":
-		return ticketpb.TicketPriority_TICKET_PRIORITY_HIGH
-	case "CRITICAL":
-		return ticketpb.TicketPriority_TICKET_PRIORITY_CRITICAL
-	default:
-		return ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM
-	}
-}
+	log.Println("🚀 Starting Ticket gRPC Microservice...")
 
-func convertStatusFromProto(status ticketpb.TicketStatus) string {
-	switch status {
-	case ticketpb.TicketStatus_TICKET_STATUS_OPEN:
-		return "OPEN"
-	case ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS:
-		return "IN_PROGRESS"
-	case ticketpb.TicketStatus_TICKET_STATUS_RESOLVED:
-		return "RESOLVED"
-	case ticketpb.TicketStatus_TICKET_STATUS_CLOSED:
-		return "CLOSED"
-	default:
-		return "OPEN"
-	}
-}
+	// Database configuration from environment variables
+	dbConfig := dbConfigFromEnv()
 
-func convertPriorityFromProto(priority ticketpb.TicketPriority) string {
-	switch priority {
-	case ticketpb.TicketPriority_TICKET_PRIORITY_LOW:
-		return "LOW"
-	case ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM:
-		return "MEDIUM"
-	case ticketpb.TicketPriority_TICKET_PRIORITY_HIGH:
-		return "HIGH"
-	case ticketpb.TicketPriority_TICKET_PRIORITY_CRITICAL:
-		return "CRITICAL"
-	default:
-		return "MEDIUM"
+	// Trace context is always propagated; spans are exported when OTEL_TRACES_EXPORTER is set
+	traceConfig := tracing.FromEnv("ticket-service")
+	shutdownTracing, err := tracing.Setup(context.Background(), traceConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
-}
-
-// CreateTicket creates a new ticket in the database
-func (s *ticketServer) CreateTicket(ctx context.Context, req *ticketpb.CreateTicketRequest) (*ticketpb.CreateTicketResponse, error) {
-	log.Printf("gRPC: Creating ticket in database - Title: %s", req.Title)
-	log.Println(req)
-	// Create database ticket
-	dbTicket := &database.Ticket{
-		ID:         uuid.New().String(),
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		Title:      req.Title,
-		Status:     "OPEN",
-		Priority:   convertPriorityFromProto(req.Priority),
-		Tags:       req.Tags,
-		ReporterID: req.ReporterId,
+	if traceConfig.Exporter != "" && traceConfig.Exporter != tracing.ExporterNone {
+		log.Printf("🔭 Exporting traces to %s", traceConfig.Exporter)
 	}
 
-	if req.Description != "" {
-		dbTicket.Description = sql.NullString{String: req.Description, Valid: true}
+	// Tickets live in PostgreSQL unless STORE_BACKEND selects SQLite or the in-memory store
+	storeBackend := getEnv("STORE_BACKEND", storeBackendPostgres)
+	store, err := openStorage(storeBackend, dbConfig)
+	if err != nil {
+		log.Fatalf("Failed to open %s store: %v", storeBackend, err)
 	}
+	defer store.close()
 
-	if req.AssigneeId != "" {
-		dbTicket.AssigneeID = sql.NullString{String: req.AssigneeId, Valid: true}
+	// Page tokens must be signed with a shared secret to stay valid across replicas and restarts
+	serviceConfig := ticketservice.Config{
+		PageTokenSecret: getEnv("PAGE_TOKEN_SECRET", ""),
+		// Status workflow, optionally loaded from a JSON file
+		WorkflowPath: getEnv("WORKFLOW_CONFIG", ""),
+	}
+	if serviceConfig.PageTokenSecret == "" {
+		log.Println("⚠️  PAGE_TOKEN_SECRET not set, page tokens will not survive restarts")
 	}
 
-	// Save to database
-	createdTicket, err := s.repo.Create(ctx, dbTicket)
+	// Tickets inserted per transaction by BulkCreateTickets
+	serviceConfig.BulkBatchSize, err = parseBulkBatchSize(getEnv("BULK_BATCH_SIZE", ""))
 	if err != nil {
-		log.Printf("gRPC: Error creating ticket in database: %v", err)
-		return nil, err
+		log.Fatalf("Invalid bulk batch size: %v", err)
 	}
 
-	log.Printf("gRPC: Ticket created successfully in database - ID: %s", createdTicket.ID)
-
-	return &ticketpb.CreateTicketResponse{
-		Ticket: dbTicketToProto(createdTicket),
-	}, nil
-}
-
-// GetTicket retrieves a ticket from the database
-func (s *ticketServer) GetTicket(ctx context.Context, req *ticketpb.GetTicketRequest) (*ticketpb.GetTicketResponse, error) {
-	log.Printf("gRPC: Getting ticket from database - ID: %s", req.Id)
-
-	ticket, err := s.repo.GetByID(ctx, req.Id)
+	// Deleted tickets stay restorable for the retention period, then the purger removes them
+	trashRetention, err := parseDuration("TRASH_RETENTION", getEnv("TRASH_RETENTION", ""), defaultTrashRetention)
 	if err != nil {
-		log.Printf("gRPC: Error getting ticket from database: %v", err)
-		return nil, err
+		log.Fatalf("Invalid trash retention: %v", err)
 	}
-
-	log.Printf("gRPC: Ticket retrieved successfully from database - ID: %s", req.Id)
-
-	return &ticketpb.GetTicketResponse{
-		Ticket: dbTicketToProto(ticket),
-	}, nil
-}
-
-// ListTickets retrieves tickets from the database with pagination
-func (s *ticketServer) ListTickets(ctx context.Context, req *ticketpb.ListTicketsRequest) (*ticketpb.ListTicketsResponse, error) {
-	log.Println("gRPC: Listing tickets from database")
-
-	limit := int(req.PageSize)
-	if limit <= 0 || limit > 100 {
-		limit = 50 // Default limit
+	purgeInterval, err := parseDuration("PURGE_INTERVAL", getEnv("PURGE_INTERVAL", ""), defaultPurgeInterval)
+	if err != nil {
+		log.Fatalf("Invalid purge interval: %v", err)
 	}
 
-	offset := 0
-	// For simplicity, we'll ignore page token and just use limit
-	// In production, you'd implement proper pagination with tokens
-
-	tickets, err := s.repo.List(ctx, limit, offset)
+	// Create TCP listener
+	port := getEnv("GRPC_PORT", "50051")
+	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		log.Printf("gRPC: Error listing tickets from database: %v", err)
-		return nil, err
+		log.Fatalf("Failed to listen on port %s: %v", port, err)
 	}
 
-	// Convert to protobuf
-	protoTickets := make([]*ticketpb.Ticket, len(tickets))
-	for i, ticket := range tickets {
-		protoTickets[i] = dbTicketToProto(ticket)
+	ticketService, err := ticketservice.NewServer(context.Background(), store.tickets, store.comments, serviceConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up the ticket service: %v", err)
 	}
 
-	log.Printf("gRPC: Listed %d tickets from database", len(tickets))
-
-	return &ticketpb.ListTicketsResponse{
-		Tickets: protoTickets,
-	}, nil
-}
-
-// UpdateTicket updates a ticket in the database
-func (s *ticketServer) UpdateTicket(ctx context.Context, req *ticketpb.UpdateTicketRequest) (*ticketpb.UpdateTicketResponse, error) {
-	log.Printf("gRPC: Updating ticket in database - ID: %s", req.Id)
-
-	// Build updates map
-	updates := make(map[string]interface{})
+	// Certificates and the authorization policy are reloaded from disk until shutdown
+	reloadCtx, stopReloading := context.WithCancel(context.Background())
+	defer stopReloading()
 
-	if req.Title != "" {
-		updates["title"] = req.Title
-	}
-	if req.Description != "" {
-		updates["description"] = req.Description
-	}
-	if req.Status != ticketpb.TicketStatus_TICKET_STATUS_UNSPECIFIED {
-		updates["status"] = convertStatusFromProto(req.Status)
-	}
-	if req.Priority != ticketpb.TicketPriority_TICKET_PRIORITY_UNSPECIFIED {
-		updates["priority"] = convertPriorityFromProto(req.Priority)
-	}
-	if req.AssigneeId != "" {
-		updates["assignee_id"] = req.AssigneeId
-	}
-	if len(req.Tags) > 0 {
-		updates["tags"] = req.Tags
-	}
-
-	// Update in database
-	updatedTicket, err := s.repo.Update(ctx, req.Id, updates)
+	// TLS, optionally verifying client certificates (mutual TLS)
+	serverTLS, verifyClients, err := loadServerTLS(reloadCtx)
 	if err != nil {
-		log.Printf("gRPC: Error updating ticket in database: %v", err)
-		return nil, err
+		log.Fatalf("Failed to set up TLS: %v", err)
+	}
+	serverOpts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	if serverTLS != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(serverTLS)))
+		log.Printf("🔒 Serving TLS, client certificates verified: %t", verifyClients)
+	} else {
+		log.Println("⚠️  TLS_CERT_FILE not set, serving plaintext")
 	}
 
-	log.Printf("gRPC: Ticket updated successfully in database - ID: %s", req.Id)
-
-	return &ticketpb.UpdateTicketResponse{
-		Ticket: dbTicketToProto(updatedTicket),
-	}, nil
-}
+	// Callers authenticate with JWT bearer tokens verified against a local JWKS file,
+	// or with a client certificate, and are then authorized by role
+	// Metrics come first so that every call is measured, including rejected ones
+	metrics := newServerMetrics(store.db, dbConfig.DBName, store.tickets)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{metrics.unaryInterceptor}
+	streamInterceptors := []grpc.StreamServerInterceptor{metrics.streamInterceptor}
+	var authz *authorizer
+	if getEnv("AUTH_DISABLED", "") == "true" {
+		log.Println("⚠️  AUTH_DISABLED is set, every caller is trusted")
+	} else {
+		var verifier *auth.Verifier
+		if jwksPath := getEnv("JWKS_PATH", ""); jwksPath != "" {
+			keys, err := auth.LoadJWKS(jwksPath)
+			if err != nil {
+				log.Fatalf("Failed to load JWKS: %v", err)
+			}
+			verifier = auth.NewVerifier(keys, getEnv("JWT_ISSUER", ""), getEnv("JWT_AUDIENCE", ""))
+			log.Printf("🔐 Authenticating bearer tokens with keys from %s", jwksPath)
+		} else if !verifyClients {
+			log.Fatalf("JWKS_PATH or TLS_CLIENT_CA_FILE must be set unless AUTH_DISABLED=true")
+		}
+		if verifyClients {
+			log.Println("🔐 Authenticating client certificates by common name")
+		}
 
-// DeleteTicket deletes a ticket from the database
-func (s *ticketServer) DeleteTicket(ctx context.Context, req *ticketpb.DeleteTicketRequest) (*ticketpb.DeleteTicketResponse, error) {
-	log.Printf("gRPC: Deleting ticket from database - ID: %s", req.Id)
+		authn := newAuthenticator(verifier)
+		unaryInterceptors = append(unaryInterceptors, authn.unaryInterceptor)
+		streamInterceptors = append(streamInterceptors, authn.streamInterceptor)
 
-	err := s.repo.Delete(ctx, req.Id)
-	if err != nil {
-		log.Printf("gRPC: Error deleting ticket from database: %v", err)
-		return &ticketpb.DeleteTicketResponse{Success: false}, nil
+		policyPath := getEnv("RBAC_POLICY", "")
+		authz, err = newAuthorizer(policyPath, store.tickets, store.comments)
+		if err != nil {
+			log.Fatalf("Failed to load authorization policy: %v", err)
+		}
+		go authz.watch(reloadCtx, policyReloadInterval)
+	}
+	unaryInterceptors = append(unaryInterceptors, ticketservice.ActorUnaryInterceptor, ticketservice.ValidationUnaryInterceptor)
+	streamInterceptors = append(streamInterceptors, ticketservice.ActorStreamInterceptor)
+	if authz != nil {
+		// Runs after validation so ownership lookups only see well-formed IDs
+		unaryInterceptors = append(unaryInterceptors, authz.unaryInterceptor)
+		streamInterceptors = append(streamInterceptors, authz.streamInterceptor)
 	}
 
-	log.Printf("gRPC: Ticket deleted successfully from database - ID: %s", req.Id)
+	// Create gRPC server
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	s := grpc.NewServer(serverOpts...)
 
-	return &ticketpb.DeleteTicketResponse{Success: true}, nil
-}
+	// Register service with database
+	ticketpb.RegisterTicketServiceServer(s, ticketService)
 
-// getEnv gets environment variable with fallback
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	log.Printf("✅ Ticket Service registered with %s backend", storeBackend)
+
+	// Health status follows a periodic database ping; the memory store is always available
+	healthServer := newHealthServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	healthCtx, stopHealthChecks := context.WithCancel(context.Background())
+	defer stopHealthChecks()
+	if store.db != nil {
+		go runHealthChecks(healthCtx, store.db, healthServer, healthCheckInterval)
+	} else {
+		for _, service := range healthCheckedServices {
+			healthServer.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+		}
 	}
-	return fallback
-}
-
-func main() {
-	log.Println("🚀 Starting Ticket gRPC Microservice with PostgreSQL...")
 
-	// Database configuration from environment variables
-	dbConfig := database.Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "ayushpandya"),
-		Password: getEnv("DB_PASSWORD", "postgres"),
-		DBName:   getEnv("DB_NAME", "ticketdb"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+	// Reflection lets tools such as grpcurl discover the API
+	if getEnv("GRPC_REFLECTION", "") == "true" {
+		reflection.Register(s)
+		log.Println("🪞 Server reflection enabled")
 	}
 
-	log.Printf("🔌 Connecting to PostgreSQL at %s:%s", dbConfig.Host, dbConfig.Port)
-
-	// Connect to database
-	db, err := database.NewConnection(dbConfig)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	// Feed WatchTickets streams from the events committed to the store
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	go store.events.Run(watchCtx, func() {
+		ticketService.HandleEvent(watchCtx)
+	})
+
+	purgeCtx, stopPurging := context.WithCancel(context.Background())
+	defer stopPurging()
+	if purgeInterval > 0 {
+		log.Printf("🗑️  Purging deleted tickets after %s, checking every %s", trashRetention, purgeInterval)
+		go runPurger(purgeCtx, store.tickets, trashRetention, purgeInterval)
+	} else {
+		log.Println("⚠️  PURGE_INTERVAL is 0, deleted tickets will never be purged")
 	}
-	defer db.Close()
 
-	// Create TCP listener
-	port := getEnv("GRPC_PORT", "50051")
-	lis, err := net.Listen("tcp", ":"+port)
-	if err != nil {
-		log.Fatalf("Failed to listen on port %s: %v", port, err)
+	// Serve Prometheus metrics over plain HTTP
+	metricsPort := getEnv("METRICS_PORT", "9090")
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}))
+	metricsServer := &http.Server{
+		Addr:              ":" + metricsPort,
+		Handler:           metricsMux,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
-
-	// Create gRPC server
-	s := grpc.NewServer()
-
-	// Register service with database
-	ticketService := newTicketServer(db)
-	ticketpb.RegisterTicketServiceServer(s, ticketService)
-
-	log.Println("✅ Ticket Service registered with PostgreSQL backend")
+	go func() {
+		log.Printf("📈 Metrics available on :%s/metrics", metricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}()
 
 	// Start server in goroutine
 	go func() {
 		log.Printf("🌐 Ticket gRPC Microservice listening on :%s", port)
-		log.Println("🎫 Ready to handle ticket operations with PostgreSQL")
+		log.Printf("🎫 Ready to handle ticket operations with %s", storeBackend)
 		log.Println(s.GetServiceInfo())
 		if err := s.Serve(lis); err != nil {
 			log.Fatalf("Failed to serve: %v", err)
@@ -318,6 +276,19 @@ func main() {
 	<-quit
 
 	log.Println("🛑 Shutting down Ticket gRPC Microservice...")
+	// Report NOT_SERVING first so load balancers stop sending new calls while we drain
+	stopHealthChecks()
+	healthServer.Shutdown()
+	// Watch streams never end on their own, so close them before draining
+	stopWatching()
+	stopPurging()
+	ticketService.Close()
 	s.GracefulStop()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	metricsServer.Shutdown(shutdownCtx)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
 	log.Println("👋 Ticket gRPC Microservice stopped")
 }
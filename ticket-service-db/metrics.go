@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"gRPC/database"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// ticketCountTimeout bounds the query run on every scrape for the ticket gauges
+const ticketCountTimeout = 5 * time.Second
+
+// serverMetrics are the Prometheus metrics of the ticket service
+type serverMetrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// newServerMetrics registers RPC, connection pool, ticket and runtime metrics
+func newServerMetrics(db *sql.DB, dbName string, repo database.TicketStore) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ticket_grpc_requests_total",
+			Help: "Completed gRPC calls by method and status code.",
+		}, []string{"method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ticket_grpc_request_duration_seconds",
+			Help:    "Time to complete gRPC calls, by method. Streams are timed until they end.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.latency,
+		&ticketCollector{repo: repo},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	// Connection pool metrics only exist for SQL backends
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+	}
+
+	return m
+}
+
+// observe records one completed call
+func (m *serverMetrics) observe(fullMethod string, start time.Time, err error) {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	m.requests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.latency.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// unaryInterceptor measures unary calls. It runs first so that calls rejected
+// by later interceptors are counted too.
+func (m *serverMetrics) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observe(info.FullMethod, start, err)
+	return resp, err
+}
+
+// streamInterceptor is the streaming counterpart of unaryInterceptor
+func (m *serverMetrics) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	m.observe(info.FullMethod, start, err)
+	return err
+}
+
+// ticketCollector reports the number of tickets by status and priority,
+// queried from the ticket store on each scrape
+type ticketCollector struct {
+	repo database.TicketStore
+}
+
+var ticketsDesc = prometheus.NewDesc(
+	"ticket_tickets",
+	"Tickets not in the trash, by status and priority.",
+	[]string{"status", "priority"}, nil,
+)
+
+func (c *ticketCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ticketsDesc
+}
+
+func (c *ticketCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), ticketCountTimeout)
+	defer cancel()
+
+	counts, err := c.repo.CountByStatusAndPriority(ctx)
+	if err != nil {
+		log.Printf("gRPC: Error counting tickets for metrics: %v", err)
+		ch <- prometheus.NewInvalidMetric(ticketsDesc, err)
+		return
+	}
+
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(ticketsDesc, prometheus.GaugeValue, float64(count.Count), count.Status, count.Priority)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gRPC/database"
+)
+
+// migrateUsage describes the arguments of the migrate command
+const migrateUsage = "usage: grpc-server migrate [up | down [steps] | status]"
+
+// migrateTimeout bounds a migrate command or the migrations run at start,
+// including the wait for another replica's migration lock
+const migrateTimeout = 5 * time.Minute
+
+// runMigrateCommand connects to the database of STORE_BACKEND and applies,
+// rolls back or lists schema migrations, returning the process exit code
+func runMigrateCommand(args []string) int {
+	action, steps := "up", 1
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch {
+	case (action == "up" || action == "status" || action == "down") && len(args) <= 1:
+	case action == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			fmt.Println(migrateUsage)
+			return 2
+		}
+		steps = n
+	default:
+		fmt.Println(migrateUsage)
+		return 2
+	}
+
+	db, migrator, err := openMigrator(getEnv("STORE_BACKEND", storeBackendPostgres), dbConfigFromEnv())
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	switch action {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		fmt.Printf("applied %d migrations\n", n)
+	case "down":
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		fmt.Printf("rolled back %d migrations\n", n)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt.Valid {
+				applied = "applied " + s.AppliedAt.Time.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+	}
+
+	return 0
+}
+
+// openMigrator connects to the database of the named backend. Opening a
+// SQLite file applies its pending migrations, so "up" has nothing left to do
+// there, and migrations rolled back by "down" return the next time the file
+// is opened by this build, which only makes sense before an older one runs.
+func openMigrator(backend string, dbConfig database.Config) (*sql.DB, *database.Migrator, error) {
+	newMigrator := database.NewMigrator
+	switch backend {
+	case storeBackendPostgres:
+	case storeBackendSQLite:
+		dbConfig.Driver = database.DriverSQLite
+		newMigrator = database.NewSQLiteMigrator
+	case storeBackendMemory:
+		return nil, nil, fmt.Errorf("the %s store backend has no schema to migrate", backend)
+	default:
+		return nil, nil, fmt.Errorf("unknown store backend %q, expected %s or %s", backend, storeBackendPostgres, storeBackendSQLite)
+	}
+
+	db, err := database.NewConnection(dbConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	migrator, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, migrator, nil
+}
+
+// migrateOnStart brings the schema up to date before the server starts
+func migrateOnStart(db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(ctx)
+	return err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gRPC/database"
+)
+
+func TestOpenMigratorFollowsStoreBackend(t *testing.T) {
+	config := database.Config{Path: filepath.Join(t.TempDir(), "tickets.db")}
+
+	db, migrator, err := openMigrator(storeBackendSQLite, config)
+	if err != nil {
+		t.Fatalf("openMigrator(sqlite) failed: %v", err)
+	}
+	defer db.Close()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.AppliedAt.Valid {
+			t.Errorf("migration %d_%s of the SQLite file is pending", s.Version, s.Name)
+		}
+	}
+
+	for _, backend := range []string{storeBackendMemory, "bogus"} {
+		if _, _, err := openMigrator(backend, config); err == nil {
+			t.Errorf("openMigrator(%s) succeeded", backend)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gRPC/auth"
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/ticketservice"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v3"
+)
+
+// policyReloadInterval is how often the policy file is checked for changes
+const policyReloadInterval = 5 * time.Second
+
+// policyRule allows a role to call a set of RPCs
+type policyRule struct {
+	Methods []string `yaml:"methods"`       // TicketService method names, or "*" for all
+	Own     bool     `yaml:"own,omitempty"` // only on tickets the caller reported or comments they wrote
+}
+
+// policyRole lists what one role may do
+type policyRole struct {
+	Allow []policyRule `yaml:"allow"`
+}
+
+// policyConfig is the on-disk representation of the authorization policy
+type policyConfig struct {
+	DefaultRole string                `yaml:"default_role"` // applied to callers whose token grants no roles
+	Roles       map[string]policyRole `yaml:"roles"`
+}
+
+// defaultPolicyConfig is used when RBAC_POLICY is not set
+var defaultPolicyConfig = policyConfig{
+	DefaultRole: "reporter",
+	Roles: map[string]policyRole{
+		"reporter": {Allow: []policyRule{
+			{Methods: []string{
+				"CreateTicket", "BulkCreateTickets", "GetTicket", "BatchGetTickets", "ListTickets",
+				"SearchTickets", "GetTicketHistory", "WatchTickets", "AddComment", "ListComments",
+			}},
+			{Methods: []string{"UpdateTicket", "TransitionTicket", "EditComment", "DeleteComment"}, Own: true},
+		}},
+		"agent": {Allow: []policyRule{
+			{Methods: []string{
+				"CreateTicket", "BulkCreateTickets", "GetTicket", "BatchGetTickets", "ListTickets",
+				"SearchTickets", "UpdateTicket", "BatchUpdateTickets", "TransitionTicket", "GetTicketHistory",
+				"WatchTickets", "AddComment", "ListComments",
+			}},
+			{Methods: []string{"EditComment", "DeleteComment"}, Own: true},
+		}},
+		"admin": {Allow: []policyRule{
+			{Methods: []string{"*"}},
+		}},
+	},
+}
+
+// ownedMethods are the RPCs that act on a single ticket or comment whose
+// owner can be looked up, and may therefore be granted with own: true
+var ownedMethods = map[string]bool{
+	"GetTicket":        true,
+	"UpdateTicket":     true,
+	"TransitionTicket": true,
+	"DeleteTicket":     true,
+	"RestoreTicket":    true,
+	"GetTicketHistory": true,
+	"AddComment":       true,
+	"ListComments":     true,
+	"EditComment":      true,
+	"DeleteComment":    true,
+}
+
+// grant is the access a role has to one method
+type grant int
+
+const (
+	grantNone grant = iota
+	grantOwn
+	grantAll
+)
+
+// policy decides which roles may call which TicketService methods
+type policy struct {
+	defaultRole string
+	grants      map[string]map[string]grant // role -> method -> grant
+}
+
+// ticketMethods returns the names of every TicketService RPC
+func ticketMethods() map[string]bool {
+	methods := make(map[string]bool)
+	for _, m := range ticketpb.TicketService_ServiceDesc.Methods {
+		methods[m.MethodName] = true
+	}
+	for _, s := range ticketpb.TicketService_ServiceDesc.Streams {
+		methods[s.StreamName] = true
+	}
+	return methods
+}
+
+// newPolicy validates a policy configuration
+func newPolicy(cfg policyConfig) (*policy, error) {
+	if _, ok := cfg.Roles[cfg.DefaultRole]; cfg.DefaultRole != "" && !ok {
+		return nil, fmt.Errorf("unknown default role %q", cfg.DefaultRole)
+	}
+
+	known := ticketMethods()
+	p := &policy{defaultRole: cfg.DefaultRole, grants: make(map[string]map[string]grant)}
+	for role, r := range cfg.Roles {
+		grants := make(map[string]grant)
+		for _, rule := range r.Allow {
+			methods := rule.Methods
+			if len(methods) == 1 && methods[0] == "*" {
+				methods = nil
+				for m := range known {
+					methods = append(methods, m)
+				}
+			}
+
+			g := grantAll
+			if rule.Own {
+				g = grantOwn
+			}
+			for _, m := range methods {
+				if !known[m] {
+					return nil, fmt.Errorf("role %s: unknown method %q", role, m)
+				}
+				if rule.Own && !ownedMethods[m] {
+					return nil, fmt.Errorf("role %s: method %s has no owner, so it cannot be granted with own", role, m)
+				}
+				if g > grants[m] {
+					grants[m] = g
+				}
+			}
+		}
+		p.grants[role] = grants
+	}
+
+	return p, nil
+}
+
+// loadPolicy reads a YAML policy file, or returns the default policy if path is empty
+func loadPolicy(path string) (*policy, error) {
+	if path == "" {
+		return newPolicy(defaultPolicyConfig)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var cfg policyConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	return newPolicy(cfg)
+}
+
+// rolesOf returns the caller's roles, falling back to the default role
+func (p *policy) rolesOf(id auth.Identity) []string {
+	if len(id.Roles) == 0 && p.defaultRole != "" {
+		return []string{p.defaultRole}
+	}
+	return id.Roles
+}
+
+// grantFor returns the widest access any of roles has to method
+func (p *policy) grantFor(roles []string, method string) grant {
+	best := grantNone
+	for _, role := range roles {
+		if g := p.grants[role][method]; g > best {
+			best = g
+		}
+	}
+	return best
+}
+
+// authorizer enforces the policy on every authenticated call
+type authorizer struct {
+	policy   atomic.Pointer[policy]
+	path     string
+	modTime  time.Time
+	repo     database.TicketStore
+	comments database.CommentStore
+}
+
+// newAuthorizer loads the policy at path, or the default policy if path is empty
+func newAuthorizer(path string, repo database.TicketStore, comments database.CommentStore) (*authorizer, error) {
+	a := &authorizer{path: path, repo: repo, comments: comments}
+
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy: %w", err)
+		}
+		a.modTime = info.ModTime()
+	}
+
+	p, err := loadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	a.policy.Store(p)
+
+	return a, nil
+}
+
+// watch reloads the policy file whenever it changes, checking every interval
+// until ctx is cancelled. An invalid file is reported and the current policy kept.
+func (a *authorizer) watch(ctx context.Context, interval time.Duration) {
+	if a.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(a.path)
+		if err != nil {
+			log.Printf("gRPC: Error checking policy file: %v", err)
+			continue
+		}
+		if info.ModTime().Equal(a.modTime) {
+			continue
+		}
+		a.modTime = info.ModTime()
+
+		p, err := loadPolicy(a.path)
+		if err != nil {
+			log.Printf("gRPC: Keeping previous policy, reload failed: %v", err)
+			continue
+		}
+		a.policy.Store(p)
+		log.Printf("🔄 Reloaded authorization policy from %s", a.path)
+	}
+}
+
+// authorize checks whether the caller in ctx may make the call. Calls
+// without an identity are only possible with authentication disabled, or to
+// the health service, and are let through. The policy covers TicketService
+// only, so any authenticated caller may use other services such as reflection.
+func (a *authorizer) authorize(ctx context.Context, fullMethod string, req interface{}) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok || !strings.HasPrefix(fullMethod, "/"+ticketpb.TicketService_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	p := a.policy.Load()
+	roles := p.rolesOf(id)
+
+	switch p.grantFor(roles, method) {
+	case grantAll:
+		return nil
+	case grantOwn:
+		if req == nil {
+			break
+		}
+		// Missing tickets and comments are denied like those of someone else,
+		// so that callers cannot probe which IDs exist
+		owner, kind, err := a.ownerOf(ctx, req)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return ticketservice.ToStatus(err, "")
+		}
+		if err == nil && owner == id.Subject {
+			return nil
+		}
+		return permissionDenied(method, roles, fmt.Sprintf("may only call %s on %s they own", method, kind))
+	}
+
+	return permissionDenied(method, roles, fmt.Sprintf("may not call %s", method))
+}
+
+// ownerOf looks up the owner of the ticket or comment a request acts on.
+// kind names what was looked up even when the lookup fails.
+func (a *authorizer) ownerOf(ctx context.Context, req interface{}) (owner, kind string, err error) {
+	var ticketID, commentID string
+	switch r := req.(type) {
+	case *ticketpb.GetTicketRequest:
+		ticketID = r.Id
+	case *ticketpb.UpdateTicketRequest:
+		ticketID = r.Id
+	case *ticketpb.TransitionTicketRequest:
+		ticketID = r.Id
+	case *ticketpb.DeleteTicketRequest:
+		ticketID = r.Id
+	case *ticketpb.RestoreTicketRequest:
+		ticketID = r.Id
+	case *ticketpb.GetTicketHistoryRequest:
+		ticketID = r.Id
+	case *ticketpb.AddCommentRequest:
+		ticketID = r.TicketId
+	case *ticketpb.ListCommentsRequest:
+		ticketID = r.TicketId
+	case *ticketpb.EditCommentRequest:
+		commentID = r.Id
+	case *ticketpb.DeleteCommentRequest:
+		commentID = r.Id
+	default:
+		return "", "", fmt.Errorf("no owner for %T", req)
+	}
+
+	if commentID != "" {
+		comment, err := a.comments.GetByID(ctx, commentID)
+		if err != nil {
+			return "", "comments", err
+		}
+		return comment.AuthorID, "comments", nil
+	}
+
+	ticket, err := a.repo.GetByIDIncludingDeleted(ctx, ticketID)
+	if err != nil {
+		return "", "tickets", err
+	}
+	return ticket.ReporterID, "tickets", nil
+}
+
+// permissionDenied explains which roles were refused and why
+func permissionDenied(method string, roles []string, reason string) error {
+	sorted := append([]string(nil), roles...)
+	sort.Strings(sorted)
+
+	subject := "callers without a role"
+	if len(sorted) > 0 {
+		subject = "role " + strings.Join(sorted, ", ")
+	}
+	msg := subject + " " + reason
+
+	return ticketservice.WithDetails(codes.PermissionDenied, msg, &errdetails.ErrorInfo{
+		Reason:   "POLICY_DENIED",
+		Domain:   "ticket.TicketService",
+		Metadata: map[string]string{"method": method, "roles": strings.Join(sorted, ",")},
+	})
+}
+
+// unaryInterceptor authorizes unary calls, including ownership checks
+func (a *authorizer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor authorizes streaming calls when they open. Streams act
+// on many tickets, so they can only be granted without ownership.
+func (a *authorizer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod, nil); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"gRPC/database"
+)
+
+// Backends selectable with STORE_BACKEND
+const (
+	storeBackendPostgres = "postgres"
+	storeBackendSQLite   = "sqlite"
+	storeBackendMemory   = "memory"
+)
+
+// storage is where the server keeps tickets, comments and their history
+type storage struct {
+	tickets  database.TicketStore
+	comments database.CommentStore
+	events   database.EventSource
+	db       *sql.DB // nil for the memory backend
+}
+
+// openStorage opens the named backend. PostgreSQL is migrated first unless
+// MIGRATE_ON_START is false, SQLite is migrated whenever the file is opened,
+// and the memory backend starts empty every time.
+func openStorage(backend string, dbConfig database.Config) (*storage, error) {
+	switch backend {
+	case storeBackendPostgres:
+		log.Printf("🔌 Connecting to PostgreSQL at %s:%s", dbConfig.Host, dbConfig.Port)
+
+		db, err := database.NewConnection(dbConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		// Bring the schema up to date unless deployments migrate separately with "migrate"
+		if getEnv("MIGRATE_ON_START", "true") == "true" {
+			if err := migrateOnStart(db); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("failed to migrate database: %w", err)
+			}
+			log.Println("✅ Database schema is up to date")
+		}
+
+		// Feeds WatchTickets streams from LISTEN/NOTIFY
+		listener, err := database.NewEventListener(dbConfig)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to listen for ticket events: %w", err)
+		}
+
+		return &storage{
+			tickets:  database.NewTicketRepository(db),
+			comments: database.NewCommentRepository(db),
+			events:   listener,
+			db:       db,
+		}, nil
+	case storeBackendSQLite:
+		log.Printf("🔌 Opening SQLite database at %s", dbConfig.Path)
+
+		dbConfig.Driver = database.DriverSQLite
+		db, err := database.NewConnection(dbConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+
+		// Changes are only announced within this process, so a SQLite file
+		// must not be shared between servers
+		repo := database.NewSQLiteRepository(db)
+		return &storage{
+			tickets:  repo,
+			comments: database.NewSQLiteCommentRepository(db),
+			events:   repo.NewEventListener(),
+			db:       db,
+		}, nil
+	case storeBackendMemory:
+		log.Println("⚠️  STORE_BACKEND is memory, tickets will not survive restarts")
+
+		store := database.NewMemoryStore()
+		return &storage{
+			tickets:  store,
+			comments: store.Comments(),
+			events:   store.NewEventListener(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q, expected %s, %s or %s", backend, storeBackendPostgres, storeBackendSQLite, storeBackendMemory)
+	}
+}
+
+// close releases the backend's connections
+func (s *storage) close() {
+	s.events.Close()
+	if s.db != nil {
+		s.db.Close()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"gRPC/tlsconfig"
+)
+
+// certReloadInterval is how often certificate files are checked for changes
+const certReloadInterval = 10 * time.Second
+
+// loadServerTLS builds the server's TLS configuration from TLS_CERT_FILE,
+// TLS_KEY_FILE, TLS_CLIENT_CA_FILE and TLS_CLIENT_AUTH, and keeps the files
+// reloaded until ctx is cancelled. It returns nil when TLS_CERT_FILE is not
+// set. verifyClients reports whether client certificates are verified.
+func loadServerTLS(ctx context.Context) (cfg *tls.Config, verifyClients bool, err error) {
+	certFile := getEnv("TLS_CERT_FILE", "")
+	if certFile == "" {
+		return nil, false, nil
+	}
+
+	clientCAFile := getEnv("TLS_CLIENT_CA_FILE", "")
+	clientAuth, err := parseClientAuth(getEnv("TLS_CLIENT_AUTH", ""), clientCAFile != "")
+	if err != nil {
+		return nil, false, err
+	}
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.Files{
+		CertFile: certFile,
+		KeyFile:  getEnv("TLS_KEY_FILE", ""),
+		CAFile:   clientCAFile,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	cfg, err = reloader.ServerConfig(clientAuth)
+	if err != nil {
+		return nil, false, err
+	}
+
+	go reloader.Watch(ctx, certReloadInterval)
+
+	return cfg, clientAuth != tls.NoClientCert, nil
+}
+
+// parseClientAuth reads TLS_CLIENT_AUTH. Client certificates are required by
+// default once a client CA is configured.
+func parseClientAuth(value string, haveClientCA bool) (tls.ClientAuthType, error) {
+	switch value {
+	case "":
+		if haveClientCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("TLS_CLIENT_AUTH must be none, optional or require, got %q", value)
+	}
+}
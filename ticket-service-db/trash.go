@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gRPC/database"
+
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// defaultTrashRetention is how long deleted tickets can be restored before they are purged
+	defaultTrashRetention = 30 * 24 * time.Hour
+	// defaultPurgeInterval is how often the purger looks for expired tickets
+	defaultPurgeInterval = time.Hour
+	// purgeBatchSize is the number of tickets removed per transaction
+	purgeBatchSize = 500
+)
+
+// parseDuration reads a duration setting such as TRASH_RETENTION, falling back when unset
+func parseDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration such as 720h, got %q", name, value)
+	}
+
+	return d, nil
+}
+
+// runPurger permanently removes tickets that have been in the trash for longer
+// than retention, checking every interval until ctx is cancelled
+func runPurger(ctx context.Context, repo database.TicketStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeExpired(ctx, repo, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired removes expired tickets in batches until none are left. Each
+// run is traced on its own, with a span per batch.
+func purgeExpired(ctx context.Context, repo database.TicketStore, retention time.Duration) {
+	ctx, span := otel.Tracer("gRPC/ticket-service-db").Start(ctx, "purge_expired_tickets")
+	defer span.End()
+
+	olderThan := time.Now().Add(-retention)
+
+	total := 0
+	for {
+		purged, err := repo.PurgeDeleted(ctx, olderThan, purgeBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("gRPC: Error purging deleted tickets: %v", err)
+			}
+			return
+		}
+		total += purged
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("🗑️  Purged %d tickets deleted before %s", total, olderThan.Format(time.RFC3339))
+	}
+}
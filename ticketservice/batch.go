@@ -0,0 +1,115 @@
+package ticketservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// BatchGetTickets retrieves several tickets by ID in one query
+func (s *Server) BatchGetTickets(ctx context.Context, req *ticketpb.BatchGetTicketsRequest) (*ticketpb.BatchGetTicketsResponse, error) {
+	log.Printf("gRPC: Batch getting tickets from database - Count: %d", len(req.Ids))
+
+	tickets, err := s.repo.GetByIDs(ctx, req.Ids)
+	if err != nil {
+		log.Printf("gRPC: Error batch getting tickets from database: %v", err)
+		return nil, ToStatus(err, "")
+	}
+
+	found := make(map[string]bool, len(tickets))
+	resp := &ticketpb.BatchGetTicketsResponse{}
+	for _, ticket := range tickets {
+		found[ticket.ID] = true
+		resp.Tickets = append(resp.Tickets, dbTicketToProto(ticket))
+	}
+	for _, id := range req.Ids {
+		if !found[id] {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+
+	if len(resp.MissingIds) > 0 && req.Mode != ticketpb.BatchMode_BATCH_MODE_BEST_EFFORT {
+		err := fmt.Errorf("%w: %s", database.ErrNotFound, strings.Join(resp.MissingIds, ", "))
+		return nil, ToStatus(err, resp.MissingIds[0])
+	}
+
+	log.Printf("gRPC: Batch get finished - found: %d, missing: %d", len(resp.Tickets), len(resp.MissingIds))
+
+	return resp, nil
+}
+
+// BatchUpdateTickets applies the same update to a list of tickets or to every
+// ticket matching a filter, in one transaction. Status changes are checked
+// against the workflow for each ticket individually.
+func (s *Server) BatchUpdateTickets(ctx context.Context, req *ticketpb.BatchUpdateTicketsRequest) (*ticketpb.BatchUpdateTicketsResponse, error) {
+	log.Printf("gRPC: Batch updating tickets in database - IDs: %d, Filter: %t", len(req.Ids), req.Filter != nil)
+
+	if (len(req.Ids) > 0) == (req.Filter != nil) {
+		return nil, ToStatus(invalidField("ids", "exactly one of ids and filter must be set"), "")
+	}
+	if req.Filter != nil && proto.Size(req.Filter) == 0 {
+		return nil, ToStatus(invalidField("filter", "must set at least one condition"), "")
+	}
+
+	filter, err := listFilterFromProto(req.Filter)
+	if err != nil {
+		return nil, ToStatus(err, "")
+	}
+
+	updates, err := ticketUpdates(&ticketpb.UpdateTicketRequest{
+		Title:          req.Title,
+		Description:    req.Description,
+		Status:         req.Status,
+		Priority:       req.Priority,
+		AssigneeId:     req.AssigneeId,
+		Tags:           req.Tags,
+		ResolutionNote: req.ResolutionNote,
+		UpdateMask:     req.UpdateMask,
+	})
+	if err != nil {
+		return nil, ToStatus(err, "")
+	}
+
+	now := time.Now()
+	atomic := req.Mode != ticketpb.BatchMode_BATCH_MODE_BEST_EFFORT
+	results, err := s.repo.UpdateBatch(ctx, req.Ids, filter, atomic, func(current *database.Ticket) (map[string]interface{}, error) {
+		ticketUpdates := make(map[string]interface{}, len(updates))
+		for field, value := range updates {
+			ticketUpdates[field] = value
+		}
+		if to, ok := ticketUpdates["status"].(string); ok {
+			if err := s.workflow.apply(current, to, ticketUpdates, now); err != nil {
+				return nil, err
+			}
+		}
+		return ticketUpdates, nil
+	})
+	if err != nil {
+		log.Printf("gRPC: Error batch updating tickets in database: %v", err)
+		return nil, ToStatus(err, "")
+	}
+
+	resp := &ticketpb.BatchUpdateTicketsResponse{}
+	for _, r := range results {
+		result := &ticketpb.BatchUpdateResult{Id: r.ID}
+		if r.Err != nil {
+			result.ErrorCode, result.ErrorMessage = resultError(r.Err, r.ID)
+			resp.FailedCount++
+		} else {
+			result.Ticket = dbTicketToProto(r.Ticket)
+			resp.UpdatedCount++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	log.Printf("gRPC: Batch update finished - updated: %d, failed: %d", resp.UpdatedCount, resp.FailedCount)
+
+	return resp, nil
+}
@@ -0,0 +1,122 @@
+package ticketservice
+
+import (
+	"errors"
+	"io"
+	"log"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/validation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultBulkBatchSize is the number of tickets BulkCreateTickets inserts per transaction
+const DefaultBulkBatchSize = 500
+
+// resultError converts the failure of one item of a bulk or batch call to
+// the gRPC code and message reported in its result
+func resultError(err error, resourceID string) (int32, string) {
+	st := status.Convert(ToStatus(err, resourceID))
+	return int32(st.Code()), st.Message()
+}
+
+// setBulkResult records the created ticket, or the failure, on result
+func setBulkResult(result *ticketpb.BulkCreateResult, ticket *database.Ticket, err error) {
+	if err != nil {
+		result.ErrorCode, result.ErrorMessage = resultError(err, "")
+		return
+	}
+	result.Ticket = dbTicketToProto(ticket)
+}
+
+// BulkCreateTickets creates every ticket sent on the stream, inserting them in
+// batches. Invalid or rejected tickets are reported per item and never abort
+// the stream; only a broken stream or a cancelled call does.
+func (s *Server) BulkCreateTickets(stream grpc.ClientStreamingServer[ticketpb.CreateTicketRequest, ticketpb.BulkCreateTicketsResponse]) error {
+	log.Println("gRPC: Bulk creating tickets in database")
+
+	ctx := stream.Context()
+	var results []*ticketpb.BulkCreateResult
+	var pending []*database.Ticket
+	var pendingResults []*ticketpb.BulkCreateResult
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		defer func() {
+			pending, pendingResults = pending[:0], pendingResults[:0]
+		}()
+
+		created, err := s.repo.CreateBatch(ctx, pending)
+		if err == nil {
+			for i, ticket := range created {
+				setBulkResult(pendingResults[i], ticket, nil)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ToStatus(ctx.Err(), "")
+		}
+
+		// One bad row fails the whole statement, so retry the batch row by row
+		// to find it and still create the others
+		log.Printf("gRPC: Batch of %d tickets failed, retrying individually: %v", len(pending), err)
+		for i, ticket := range pending {
+			created, err := s.repo.Create(ctx, ticket)
+			if err != nil && ctx.Err() != nil {
+				return ToStatus(ctx.Err(), "")
+			}
+			setBulkResult(pendingResults[i], created, err)
+		}
+		return nil
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		result := &ticketpb.BulkCreateResult{Index: int32(len(results))}
+		results = append(results, result)
+
+		if err := validation.Validate(req); err != nil {
+			setBulkResult(result, nil, err)
+			continue
+		}
+
+		pending = append(pending, s.newDBTicket(req))
+		pendingResults = append(pendingResults, result)
+
+		if len(pending) >= s.bulkBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	resp := &ticketpb.BulkCreateTicketsResponse{Results: results}
+	for _, result := range results {
+		if result.ErrorCode == int32(codes.OK) {
+			resp.CreatedCount++
+		} else {
+			resp.FailedCount++
+		}
+	}
+
+	log.Printf("gRPC: Bulk create finished - created: %d, failed: %d", resp.CreatedCount, resp.FailedCount)
+
+	return stream.SendAndClose(resp)
+}
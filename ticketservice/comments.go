@@ -0,0 +1,128 @@
+package ticketservice
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func dbCommentToProto(dbComment *database.Comment) *ticketpb.Comment {
+	comment := &ticketpb.Comment{
+		Id:        dbComment.ID,
+		TicketId:  dbComment.TicketID,
+		AuthorId:  dbComment.AuthorID,
+		Body:      dbComment.Body,
+		CreatedAt: timestamppb.New(dbComment.CreatedAt),
+	}
+
+	if dbComment.EditedAt.Valid {
+		comment.EditedAt = timestamppb.New(dbComment.EditedAt.Time)
+	}
+
+	return comment
+}
+
+// AddComment adds a comment to a ticket's discussion thread
+func (s *Server) AddComment(ctx context.Context, req *ticketpb.AddCommentRequest) (*ticketpb.AddCommentResponse, error) {
+	log.Printf("gRPC: Adding comment to ticket in database - Ticket ID: %s", req.TicketId)
+
+	comment, err := s.comments.Create(ctx, &database.Comment{
+		ID:        uuid.New().String(),
+		TicketID:  req.TicketId,
+		AuthorID:  req.AuthorId,
+		Body:      req.Body,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("gRPC: Error adding comment in database: %v", err)
+		return nil, ToStatus(err, req.TicketId)
+	}
+
+	log.Printf("gRPC: Comment added successfully in database - ID: %s", comment.ID)
+
+	return &ticketpb.AddCommentResponse{
+		Comment: dbCommentToProto(comment),
+	}, nil
+}
+
+// ListComments lists the comments on a ticket, oldest first
+func (s *Server) ListComments(ctx context.Context, req *ticketpb.ListCommentsRequest) (*ticketpb.ListCommentsResponse, error) {
+	log.Printf("gRPC: Listing comments from database - Ticket ID: %s", req.TicketId)
+
+	limit := int(req.PageSize)
+	if limit <= 0 || limit > 100 {
+		limit = 50 // Default limit
+	}
+
+	scope := "comments/" + req.TicketId
+	cursor, err := s.pageTokens.decode(req.PageToken, scope)
+	if err != nil {
+		return nil, ToStatus(invalidField("page_token", err.Error()), req.TicketId)
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	comments, err := s.comments.List(ctx, req.TicketId, limit+1, cursor)
+	if err != nil {
+		log.Printf("gRPC: Error listing comments from database: %v", err)
+		return nil, ToStatus(err, req.TicketId)
+	}
+
+	nextPageToken := ""
+	if len(comments) > limit {
+		comments = comments[:limit]
+		last := comments[len(comments)-1]
+		nextPageToken, err = s.pageTokens.encode(database.ListCursor{CreatedAt: last.CreatedAt, ID: last.ID}, scope)
+		if err != nil {
+			return nil, ToStatus(err, req.TicketId)
+		}
+	}
+
+	protoComments := make([]*ticketpb.Comment, len(comments))
+	for i, comment := range comments {
+		protoComments[i] = dbCommentToProto(comment)
+	}
+
+	log.Printf("gRPC: Listed %d comments from database", len(comments))
+
+	return &ticketpb.ListCommentsResponse{
+		Comments:      protoComments,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// EditComment replaces the body of a comment
+func (s *Server) EditComment(ctx context.Context, req *ticketpb.EditCommentRequest) (*ticketpb.EditCommentResponse, error) {
+	log.Printf("gRPC: Editing comment in database - ID: %s", req.Id)
+
+	comment, err := s.comments.Update(ctx, req.Id, req.Body)
+	if err != nil {
+		log.Printf("gRPC: Error editing comment in database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Comment edited successfully in database - ID: %s", req.Id)
+
+	return &ticketpb.EditCommentResponse{
+		Comment: dbCommentToProto(comment),
+	}, nil
+}
+
+// DeleteComment deletes a comment
+func (s *Server) DeleteComment(ctx context.Context, req *ticketpb.DeleteCommentRequest) (*ticketpb.DeleteCommentResponse, error) {
+	log.Printf("gRPC: Deleting comment from database - ID: %s", req.Id)
+
+	if err := s.comments.Delete(ctx, req.Id); err != nil {
+		log.Printf("gRPC: Error deleting comment from database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Comment deleted successfully from database - ID: %s", req.Id)
+
+	return &ticketpb.DeleteCommentResponse{Success: true}, nil
+}
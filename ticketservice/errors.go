@@ -0,0 +1,133 @@
+package ticketservice
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"gRPC/database"
+	"gRPC/validation"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// columnFields maps database columns to the request fields they are populated from
+var columnFields = map[string]string{
+	"title":           "title",
+	"description":     "description",
+	"status":          "status",
+	"priority":        "priority",
+	"assignee_id":     "assignee_id",
+	"tags":            "tags",
+	"reporter_id":     "reporter_id",
+	"resolution_note": "resolution_note",
+}
+
+// invalidField returns an error that ToStatus turns into InvalidArgument with a BadRequest detail
+func invalidField(field, description string) error {
+	return validation.Invalid(field, description)
+}
+
+// ToStatus translates service and repository errors into gRPC status errors.
+// resourceID names the ticket or comment the request was about and may be empty.
+func ToStatus(err error, resourceID string) error {
+	if err == nil {
+		return nil
+	}
+
+	// Errors that already carry a status are passed through untouched
+	if st, ok := status.FromError(err); ok {
+		return st.Err()
+	}
+
+	var violations validation.Errors
+	if errors.As(err, &violations) {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations,
+				&errdetails.BadRequest_FieldViolation{Field: v.Field, Description: v.Description})
+		}
+		return WithDetails(codes.InvalidArgument, violations.Error(), badRequest)
+	}
+
+	var transitionErr *transitionError
+	if errors.As(err, &transitionErr) {
+		return WithDetails(codes.FailedPrecondition, transitionErr.Error(), &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{
+				{Type: "STATUS_TRANSITION", Subject: "ticket/" + resourceID, Description: transitionErr.Error()},
+			},
+		})
+	}
+
+	var constraintErr *database.ConstraintError
+	errors.As(err, &constraintErr)
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return WithDetails(codes.NotFound, err.Error(), &errdetails.ResourceInfo{
+			ResourceType: "ticket",
+			ResourceName: resourceID,
+			Description:  "the ticket does not exist",
+		})
+	case errors.Is(err, database.ErrCommentNotFound):
+		return WithDetails(codes.NotFound, err.Error(), &errdetails.ResourceInfo{
+			ResourceType: "comment",
+			ResourceName: resourceID,
+			Description:  "the comment does not exist",
+		})
+	case errors.Is(err, database.ErrAlreadyExists):
+		return WithDetails(codes.AlreadyExists, err.Error(), &errdetails.ResourceInfo{
+			ResourceType: "ticket",
+			ResourceName: resourceID,
+			Description:  "a ticket with this identity already exists",
+		})
+	case errors.Is(err, database.ErrInvalidArgument):
+		field := ""
+		if constraintErr != nil {
+			field = columnFields[constraintErr.Column]
+		}
+		return WithDetails(codes.InvalidArgument, err.Error(), &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: field, Description: err.Error()},
+			},
+		})
+	case errors.Is(err, database.ErrVersionConflict):
+		return WithDetails(codes.Aborted, err.Error(), &errdetails.ErrorInfo{
+			Reason:   "VERSION_MISMATCH",
+			Domain:   "ticket.TicketService",
+			Metadata: map[string]string{"ticket_id": resourceID},
+		})
+	case errors.Is(err, database.ErrFailedPrecondition):
+		violationType := "CONSTRAINT"
+		if constraintErr != nil {
+			violationType = constraintErr.Constraint
+		}
+		return WithDetails(codes.FailedPrecondition, err.Error(), &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{
+				{Type: violationType, Subject: resourceID, Description: err.Error()},
+			},
+		})
+	case errors.Is(err, database.ErrUnavailable):
+		return status.Error(codes.Unavailable, "ticket storage is temporarily unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		log.Printf("gRPC: Unclassified error: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// WithDetails builds a status error carrying the given detail, falling back
+// to a bare status if the detail cannot be attached
+func WithDetails(code codes.Code, msg string, detail protoadapt.MessageV1) error {
+	st, err := status.New(code, msg).WithDetails(detail)
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
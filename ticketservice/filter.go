@@ -0,0 +1,124 @@
+package ticketservice
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// listFilterFromProto converts a TicketFilter, which may be nil
+func listFilterFromProto(f *ticketpb.TicketFilter) (database.ListFilter, error) {
+	var filter database.ListFilter
+	if f == nil {
+		return filter, nil
+	}
+
+	for _, st := range f.Statuses {
+		status, err := filterStatusFromProto("filter.statuses", st)
+		if err != nil {
+			return filter, err
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	for _, p := range f.Priorities {
+		priority, err := filterPriorityFromProto("filter.priorities", p)
+		if err != nil {
+			return filter, err
+		}
+		filter.Priorities = append(filter.Priorities, priority)
+	}
+	filter.AssigneeID = f.AssigneeId
+	filter.ReporterID = f.ReporterId
+	filter.Tags = f.Tags
+	filter.CreatedAfter = timeOrZero(f.CreatedAfter)
+	filter.CreatedBefore = timeOrZero(f.CreatedBefore)
+	filter.UpdatedAfter = timeOrZero(f.UpdatedAfter)
+	filter.UpdatedBefore = timeOrZero(f.UpdatedBefore)
+
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return filter, invalidField("filter.created_after", "must be before filter.created_before")
+	}
+	if f.UpdatedAfter != nil && f.UpdatedBefore != nil && !filter.UpdatedAfter.Before(filter.UpdatedBefore) {
+		return filter, invalidField("filter.updated_after", "must be before filter.updated_before")
+	}
+
+	return filter, nil
+}
+
+// filterStatusFromProto converts a status to filter on. Unlike
+// convertStatusFromProto it has no fallback, so that a filter on
+// UNSPECIFIED or an undefined value is rejected rather than matching OPEN.
+func filterStatusFromProto(field string, st ticketpb.TicketStatus) (string, error) {
+	if st == ticketpb.TicketStatus_TICKET_STATUS_UNSPECIFIED {
+		return "", invalidField(field, "must not contain TICKET_STATUS_UNSPECIFIED")
+	}
+	if _, ok := ticketpb.TicketStatus_name[int32(st)]; !ok {
+		return "", invalidField(field, fmt.Sprintf("unknown status %d", st))
+	}
+	return convertStatusFromProto(st), nil
+}
+
+// filterPriorityFromProto is the priority counterpart of filterStatusFromProto
+func filterPriorityFromProto(field string, p ticketpb.TicketPriority) (string, error) {
+	if p == ticketpb.TicketPriority_TICKET_PRIORITY_UNSPECIFIED {
+		return "", invalidField(field, "must not contain TICKET_PRIORITY_UNSPECIFIED")
+	}
+	if _, ok := ticketpb.TicketPriority_name[int32(p)]; !ok {
+		return "", invalidField(field, fmt.Sprintf("unknown priority %d", p))
+	}
+	return convertPriorityFromProto(p), nil
+}
+
+// listQueryFromProto converts the filter and sort options of a ListTicketsRequest
+func listQueryFromProto(req *ticketpb.ListTicketsRequest) (database.ListQuery, error) {
+	var q database.ListQuery
+
+	filter, err := listFilterFromProto(req.Filter)
+	if err != nil {
+		return q, err
+	}
+	q.Filter = filter
+
+	switch req.SortBy {
+	case ticketpb.TicketSortField_TICKET_SORT_FIELD_UNSPECIFIED, ticketpb.TicketSortField_TICKET_SORT_FIELD_CREATED_AT:
+		q.SortBy = database.SortByCreatedAt
+	case ticketpb.TicketSortField_TICKET_SORT_FIELD_UPDATED_AT:
+		q.SortBy = database.SortByUpdatedAt
+	case ticketpb.TicketSortField_TICKET_SORT_FIELD_PRIORITY:
+		q.SortBy = database.SortByPriority
+	default:
+		return q, invalidField("sort_by", fmt.Sprintf("unknown sort field %v", req.SortBy))
+	}
+
+	q.Ascending = req.SortDirection == ticketpb.SortDirection_SORT_DIRECTION_ASC
+
+	return q, nil
+}
+
+// pageScope fingerprints the query-defining fields of a request so that page
+// tokens cannot be replayed against a different query. Callers pass a copy of
+// the request with the page size and token cleared.
+func pageScope(m proto.Message) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal page scope: %w", err)
+	}
+
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+// timeOrZero converts an optional timestamp, mapping nil to the zero time
+func timeOrZero(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
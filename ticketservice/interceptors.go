@@ -0,0 +1,68 @@
+package ticketservice
+
+import (
+	"context"
+
+	"gRPC/auth"
+	"gRPC/database"
+	"gRPC/validation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// ActorMetadataKey is the request header naming the caller recorded in ticket
+// history. It is only honoured when authentication is disabled.
+const ActorMetadataKey = "x-actor-id"
+
+// actorContext attributes the writes made by a request to the authenticated
+// caller or, without authentication, to the actor named in its metadata
+func actorContext(ctx context.Context) context.Context {
+	if id, ok := auth.FromContext(ctx); ok {
+		return database.WithActor(ctx, id.Subject)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if actors := md.Get(ActorMetadataKey); len(actors) > 0 {
+			return database.WithActor(ctx, actors[0])
+		}
+	}
+	return ctx
+}
+
+// ActorUnaryInterceptor sets the actor of unary calls
+func ActorUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(actorContext(ctx), req)
+}
+
+// ActorStreamInterceptor is the streaming counterpart of ActorUnaryInterceptor
+func ActorStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, StreamWithContext(ss, actorContext(ss.Context())))
+}
+
+// StreamWithContext wraps ss so that handlers see ctx, a context derived from
+// the stream's by an interceptor
+func StreamWithContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &contextStream{ServerStream: ss, ctx: ctx}
+}
+
+// contextStream is a ServerStream carrying a context derived by an interceptor
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// ValidationUnaryInterceptor rejects requests that break the (ticket.rules)
+// declared in the proto definitions before they reach a handler
+func ValidationUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if msg, ok := req.(proto.Message); ok {
+		if err := validation.Validate(msg); err != nil {
+			return nil, ToStatus(err, "")
+		}
+	}
+	return handler(ctx, req)
+}
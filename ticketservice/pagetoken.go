@@ -0,0 +1,116 @@
+package ticketservice
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gRPC/database"
+)
+
+// errInvalidPageToken is returned when a page token is malformed or has been tampered with
+var errInvalidPageToken = errors.New("invalid page token")
+
+// pageTokenPayload is the signed content of a page token
+type pageTokenPayload struct {
+	CreatedAt time.Time `json:"c"`
+	UpdatedAt time.Time `json:"u"`
+	Priority  string    `json:"p,omitempty"`
+	DeletedAt time.Time `json:"d"`
+	Rank      float64   `json:"r,omitempty"`
+	ID        string    `json:"i"`
+	Scope     string    `json:"s,omitempty"`
+}
+
+// pageTokenCodec encodes keyset cursors into opaque, HMAC-signed page tokens
+type pageTokenCodec struct {
+	secret []byte
+}
+
+// newPageTokenCodec creates a codec signing tokens with the given secret.
+// An empty secret is replaced with a random one, which means tokens are only
+// valid for the lifetime of this process.
+func newPageTokenCodec(secret string) (*pageTokenCodec, error) {
+	if secret != "" {
+		return &pageTokenCodec{secret: []byte(secret)}, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate page token secret: %w", err)
+	}
+	return &pageTokenCodec{secret: key}, nil
+}
+
+// encode turns a cursor into a page token bound to the given scope, which
+// identifies the filter and sort order the cursor belongs to
+func (c *pageTokenCodec) encode(cursor database.ListCursor, scope string) (string, error) {
+	payload, err := json.Marshal(pageTokenPayload{
+		CreatedAt: cursor.CreatedAt,
+		UpdatedAt: cursor.UpdatedAt,
+		Priority:  cursor.Priority,
+		DeletedAt: cursor.DeletedAt,
+		Rank:      cursor.Rank,
+		ID:        cursor.ID,
+		Scope:     scope,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal page token: %w", err)
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body)), nil
+}
+
+// decode verifies a page token against the scope it is used with and
+// returns its cursor. An empty token yields a nil cursor.
+func (c *pageTokenCodec) decode(token, scope string) (*database.ListCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errInvalidPageToken
+	}
+
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, c.sign(body)) {
+		return nil, errInvalidPageToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, errInvalidPageToken
+	}
+
+	var p pageTokenPayload
+	if err := json.Unmarshal(payload, &p); err != nil || p.ID == "" {
+		return nil, errInvalidPageToken
+	}
+
+	if p.Scope != scope {
+		return nil, fmt.Errorf("%w: token was issued for a different query", errInvalidPageToken)
+	}
+
+	return &database.ListCursor{
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+		Priority:  p.Priority,
+		DeletedAt: p.DeletedAt,
+		Rank:      p.Rank,
+		ID:        p.ID,
+	}, nil
+}
+
+func (c *pageTokenCodec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,550 @@
+// Package ticketservice implements the TicketService gRPC API on top of the
+// ticket and comment stores of package database
+package ticketservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Config holds the settings of a Server. Zero values select the defaults.
+type Config struct {
+	// PageTokenSecret signs page tokens. Without it a random secret is used,
+	// and tokens do not survive restarts or work across replicas.
+	PageTokenSecret string
+	// WorkflowPath names a JSON status workflow; empty uses the default one
+	WorkflowPath string
+	// BulkBatchSize is the number of tickets BulkCreateTickets inserts per
+	// transaction, DefaultBulkBatchSize when zero
+	BulkBatchSize int
+}
+
+// Server implements the TicketService gRPC service on a ticket store
+type Server struct {
+	ticketpb.UnimplementedTicketServiceServer
+	repo       database.TicketStore
+	comments   database.CommentStore
+	pageTokens *pageTokenCodec
+	workflow   *workflow
+	watch      *watchHub
+
+	bulkBatchSize int
+}
+
+// NewServer creates a new ticket server backed by the given stores.
+// WatchTickets streams only see the events committed after it returns,
+// once HandleEvent announces them.
+func NewServer(ctx context.Context, repo database.TicketStore, comments database.CommentStore, config Config) (*Server, error) {
+	pageTokens, err := newPageTokenCodec(config.PageTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	wf, err := loadWorkflow(config.WorkflowPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bulkBatchSize := config.BulkBatchSize
+	if bulkBatchSize == 0 {
+		bulkBatchSize = DefaultBulkBatchSize
+	}
+	if bulkBatchSize < 0 || bulkBatchSize > database.MaxBatchSize {
+		return nil, fmt.Errorf("bulk batch size must be between 1 and %d, got %d", database.MaxBatchSize, bulkBatchSize)
+	}
+
+	watch, err := newWatchHub(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		repo:       repo,
+		comments:   comments,
+		pageTokens: pageTokens,
+		workflow:   wf,
+		watch:      watch,
+
+		bulkBatchSize: bulkBatchSize,
+	}, nil
+}
+
+// HandleEvent re-reads the ticket event feed and delivers what is new to
+// WatchTickets streams. It is meant to be called from database.EventSource.Run
+// whenever events may have been committed, including after the source has
+// reconnected. Events are delivered in position order, so some may wait for
+// transactions that are still in flight.
+func (s *Server) HandleEvent(ctx context.Context) {
+	s.watch.handle(ctx)
+}
+
+// Close ends every WatchTickets stream, which would otherwise keep a
+// graceful stop of the gRPC server waiting
+func (s *Server) Close() {
+	s.watch.close()
+}
+
+// Helper functions to convert between protobuf and database models
+func dbTicketToProto(dbTicket *database.Ticket) *ticketpb.Ticket {
+	ticket := &ticketpb.Ticket{
+		Id:        dbTicket.ID,
+		Title:     dbTicket.Title,
+		Status:    convertStatusToProto(dbTicket.Status),
+		Priority:  convertPriorityToProto(dbTicket.Priority),
+		Tags:      dbTicket.Tags,
+		CreatedAt: timestamppb.New(dbTicket.CreatedAt),
+		UpdatedAt: timestamppb.New(dbTicket.UpdatedAt),
+		Version:   dbTicket.Version,
+	}
+
+	if dbTicket.Description.Valid {
+		ticket.Description = dbTicket.Description.String
+	}
+
+	if dbTicket.AssigneeID.Valid {
+		ticket.AssigneeId = dbTicket.AssigneeID.String
+	}
+
+	if dbTicket.ResolutionNote.Valid {
+		ticket.ResolutionNote = dbTicket.ResolutionNote.String
+	}
+
+	if dbTicket.ResolvedAt.Valid {
+		ticket.ResolvedAt = timestamppb.New(dbTicket.ResolvedAt.Time)
+	}
+
+	if dbTicket.ClosedAt.Valid {
+		ticket.ClosedAt = timestamppb.New(dbTicket.ClosedAt.Time)
+	}
+
+	if dbTicket.DeletedAt.Valid {
+		ticket.DeletedAt = timestamppb.New(dbTicket.DeletedAt.Time)
+	}
+
+	return ticket
+}
+
+func dbEventToProto(dbEvent *database.TicketEvent) *ticketpb.TicketEvent {
+	event := &ticketpb.TicketEvent{
+		Id:        dbEvent.ID,
+		TicketId:  dbEvent.TicketID,
+		Type:      convertEventTypeToProto(dbEvent.Type),
+		Actor:     dbEvent.Actor,
+		CreatedAt: timestamppb.New(dbEvent.CreatedAt),
+	}
+
+	for _, change := range dbEvent.Changes {
+		event.Changes = append(event.Changes, &ticketpb.FieldChange{
+			Field:  change.Field,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+
+	return event
+}
+
+func convertEventTypeToProto(eventType string) ticketpb.TicketEventType {
+	switch eventType {
+	case database.EventCreated:
+		return ticketpb.TicketEventType_TICKET_EVENT_TYPE_CREATED
+	case database.EventUpdated:
+		return ticketpb.TicketEventType_TICKET_EVENT_TYPE_UPDATED
+	case database.EventDeleted:
+		return ticketpb.TicketEventType_TICKET_EVENT_TYPE_DELETED
+	case database.EventRestored:
+		return ticketpb.TicketEventType_TICKET_EVENT_TYPE_RESTORED
+	case database.EventPurged:
+		return ticketpb.TicketEventType_TICKET_EVENT_TYPE_PURGED
+	default:
+		return ticketpb.TicketEventType_TICKET_EVENT_TYPE_UNSPECIFIED
+	}
+}
+
+func convertStatusToProto(status string) ticketpb.TicketStatus {
+	switch status {
+	case "OPEN":
+		return ticketpb.TicketStatus_TICKET_STATUS_OPEN
+	case "IN_PROGRESS":
+		return ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS
+	case "RESOLVED":
+		return ticketpb.TicketStatus_TICKET_STATUS_RESOLVED
+	case "CLOSED":
+		return ticketpb.TicketStatus_TICKET_STATUS_CLOSED
+	default:
+		return ticketpb.TicketStatus_TICKET_STATUS_OPEN
+	}
+}
+
+func convertPriorityToProto(priority string) ticketpb.TicketPriority {
+	switch priority {
+	case "LOW":
+		return ticketpb.TicketPriority_TICKET_PRIORITY_LOW
+	case "MEDIUM":
+		return ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM
+	case "HIGH":
+		return ticketpb.TicketPriority_TICKET_PRIORITY_HIGH
+	case "CRITICAL":
+		return ticketpb.TicketPriority_TICKET_PRIORITY_CRITICAL
+	default:
+		return ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM
+	}
+}
+
+func convertStatusFromProto(status ticketpb.TicketStatus) string {
+	switch status {
+	case ticketpb.TicketStatus_TICKET_STATUS_OPEN:
+		return "OPEN"
+	case ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS:
+		return "IN_PROGRESS"
+	case ticketpb.TicketStatus_TICKET_STATUS_RESOLVED:
+		return "RESOLVED"
+	case ticketpb.TicketStatus_TICKET_STATUS_CLOSED:
+		return "CLOSED"
+	default:
+		return "OPEN"
+	}
+}
+
+func convertPriorityFromProto(priority ticketpb.TicketPriority) string {
+	switch priority {
+	case ticketpb.TicketPriority_TICKET_PRIORITY_LOW:
+		return "LOW"
+	case ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM:
+		return "MEDIUM"
+	case ticketpb.TicketPriority_TICKET_PRIORITY_HIGH:
+		return "HIGH"
+	case ticketpb.TicketPriority_TICKET_PRIORITY_CRITICAL:
+		return "CRITICAL"
+	default:
+		return "MEDIUM"
+	}
+}
+
+// CreateTicket creates a new ticket in the database
+func (s *Server) CreateTicket(ctx context.Context, req *ticketpb.CreateTicketRequest) (*ticketpb.CreateTicketResponse, error) {
+	log.Printf("gRPC: Creating ticket in database - Title: %s", req.Title)
+	log.Println(req)
+	dbTicket := s.newDBTicket(req)
+
+	// Save to database
+	createdTicket, err := s.repo.Create(ctx, dbTicket)
+	if err != nil {
+		log.Printf("gRPC: Error creating ticket in database: %v", err)
+		return nil, ToStatus(err, dbTicket.ID)
+	}
+
+	log.Printf("gRPC: Ticket created successfully in database - ID: %s", createdTicket.ID)
+
+	return &ticketpb.CreateTicketResponse{
+		Ticket: dbTicketToProto(createdTicket),
+	}, nil
+}
+
+// newDBTicket builds a new database ticket in the workflow's initial status
+func (s *Server) newDBTicket(req *ticketpb.CreateTicketRequest) *database.Ticket {
+	now := time.Now()
+	dbTicket := &database.Ticket{
+		ID:         uuid.New().String(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Title:      req.Title,
+		Status:     s.workflow.initial,
+		Priority:   convertPriorityFromProto(req.Priority),
+		Tags:       req.Tags,
+		ReporterID: req.ReporterId,
+	}
+
+	if req.Description != "" {
+		dbTicket.Description = sql.NullString{String: req.Description, Valid: true}
+	}
+
+	if req.AssigneeId != "" {
+		dbTicket.AssigneeID = sql.NullString{String: req.AssigneeId, Valid: true}
+	}
+
+	return dbTicket
+}
+
+// GetTicket retrieves a ticket from the database
+func (s *Server) GetTicket(ctx context.Context, req *ticketpb.GetTicketRequest) (*ticketpb.GetTicketResponse, error) {
+	log.Printf("gRPC: Getting ticket from database - ID: %s", req.Id)
+
+	getTicket := s.repo.GetByID
+	if req.IncludeDeleted {
+		getTicket = s.repo.GetByIDIncludingDeleted
+	}
+
+	ticket, err := getTicket(ctx, req.Id)
+	if err != nil {
+		log.Printf("gRPC: Error getting ticket from database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Ticket retrieved successfully from database - ID: %s", req.Id)
+
+	return &ticketpb.GetTicketResponse{
+		Ticket: dbTicketToProto(ticket),
+	}, nil
+}
+
+// ListTickets retrieves tickets from the database with pagination
+func (s *Server) ListTickets(ctx context.Context, req *ticketpb.ListTicketsRequest) (*ticketpb.ListTicketsResponse, error) {
+	log.Println("gRPC: Listing tickets from database")
+
+	limit := int(req.PageSize)
+	if limit <= 0 || limit > 100 {
+		limit = 50 // Default limit
+	}
+
+	query, err := listQueryFromProto(req)
+	if err != nil {
+		return nil, ToStatus(err, "")
+	}
+
+	scope, err := pageScope(&ticketpb.ListTicketsRequest{
+		Filter:        req.Filter,
+		SortBy:        req.SortBy,
+		SortDirection: req.SortDirection,
+	})
+	if err != nil {
+		return nil, ToStatus(err, "")
+	}
+
+	cursor, err := s.pageTokens.decode(req.PageToken, scope)
+	if err != nil {
+		return nil, ToStatus(invalidField("page_token", err.Error()), "")
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	tickets, err := s.repo.List(ctx, query, limit+1, cursor)
+	if err != nil {
+		log.Printf("gRPC: Error listing tickets from database: %v", err)
+		return nil, ToStatus(err, "")
+	}
+
+	nextPageToken := ""
+	if len(tickets) > limit {
+		tickets = tickets[:limit]
+		nextPageToken, err = s.pageTokens.encode(database.CursorFor(tickets[len(tickets)-1]), scope)
+		if err != nil {
+			return nil, ToStatus(err, "")
+		}
+	}
+
+	// Convert to protobuf
+	protoTickets := make([]*ticketpb.Ticket, len(tickets))
+	for i, ticket := range tickets {
+		protoTickets[i] = dbTicketToProto(ticket)
+	}
+
+	log.Printf("gRPC: Listed %d tickets from database", len(tickets))
+
+	return &ticketpb.ListTicketsResponse{
+		Tickets:       protoTickets,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// SearchTickets ranks tickets by relevance of their title and description to free text
+func (s *Server) SearchTickets(ctx context.Context, req *ticketpb.SearchTicketsRequest) (*ticketpb.SearchTicketsResponse, error) {
+	log.Printf("gRPC: Searching tickets in database - Query: %s", req.Query)
+
+	limit := int(req.PageSize)
+	if limit <= 0 || limit > 100 {
+		limit = 50 // Default limit
+	}
+
+	filter, err := listFilterFromProto(req.Filter)
+	if err != nil {
+		return nil, ToStatus(err, "")
+	}
+
+	scope, err := pageScope(&ticketpb.SearchTicketsRequest{
+		Query:  req.Query,
+		Filter: req.Filter,
+	})
+	if err != nil {
+		return nil, ToStatus(err, "")
+	}
+
+	cursor, err := s.pageTokens.decode(req.PageToken, scope)
+	if err != nil {
+		return nil, ToStatus(invalidField("page_token", err.Error()), "")
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	results, err := s.repo.Search(ctx, req.Query, filter, limit+1, cursor)
+	if err != nil {
+		log.Printf("gRPC: Error searching tickets in database: %v", err)
+		return nil, ToStatus(err, "")
+	}
+
+	nextPageToken := ""
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[len(results)-1]
+		nextPageToken, err = s.pageTokens.encode(database.ListCursor{Rank: last.Rank, ID: last.Ticket.ID}, scope)
+		if err != nil {
+			return nil, ToStatus(err, "")
+		}
+	}
+
+	protoResults := make([]*ticketpb.SearchResult, len(results))
+	for i, result := range results {
+		protoResults[i] = &ticketpb.SearchResult{
+			Ticket:             dbTicketToProto(result.Ticket),
+			Rank:               float32(result.Rank),
+			TitleSnippet:       result.TitleSnippet,
+			DescriptionSnippet: result.DescriptionSnippet,
+		}
+	}
+
+	log.Printf("gRPC: Found %d tickets matching search", len(results))
+
+	return &ticketpb.SearchTicketsResponse{
+		Results:       protoResults,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// UpdateTicket updates a ticket in the database
+func (s *Server) UpdateTicket(ctx context.Context, req *ticketpb.UpdateTicketRequest) (*ticketpb.UpdateTicketResponse, error) {
+	log.Printf("gRPC: Updating ticket in database - ID: %s", req.Id)
+
+	updates, err := ticketUpdates(req)
+	if err != nil {
+		return nil, ToStatus(err, req.Id)
+	}
+
+	expectedVersion, err := s.applyWorkflow(ctx, req.Id, updates, req.ExpectedVersion)
+	if err != nil {
+		log.Printf("gRPC: Rejected status change for ticket %s: %v", req.Id, err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	// Update in database
+	updatedTicket, err := s.repo.Update(ctx, req.Id, updates, expectedVersion)
+	if err != nil {
+		log.Printf("gRPC: Error updating ticket in database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Ticket updated successfully in database - ID: %s", req.Id)
+
+	return &ticketpb.UpdateTicketResponse{
+		Ticket: dbTicketToProto(updatedTicket),
+	}, nil
+}
+
+// TransitionTicket moves a ticket to a new status following the configured workflow
+func (s *Server) TransitionTicket(ctx context.Context, req *ticketpb.TransitionTicketRequest) (*ticketpb.TransitionTicketResponse, error) {
+	log.Printf("gRPC: Transitioning ticket in database - ID: %s, Status: %s", req.Id, req.Status)
+
+	updates := map[string]interface{}{
+		"status": convertStatusFromProto(req.Status),
+	}
+	if req.ResolutionNote != "" {
+		updates["resolution_note"] = req.ResolutionNote
+	}
+
+	expectedVersion, err := s.applyWorkflow(ctx, req.Id, updates, req.ExpectedVersion)
+	if err != nil {
+		log.Printf("gRPC: Rejected status change for ticket %s: %v", req.Id, err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	updatedTicket, err := s.repo.Update(ctx, req.Id, updates, expectedVersion)
+	if err != nil {
+		log.Printf("gRPC: Error transitioning ticket in database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Ticket transitioned successfully in database - ID: %s", req.Id)
+
+	return &ticketpb.TransitionTicketResponse{
+		Ticket: dbTicketToProto(updatedTicket),
+	}, nil
+}
+
+// DeleteTicket deletes a ticket from the database
+func (s *Server) DeleteTicket(ctx context.Context, req *ticketpb.DeleteTicketRequest) (*ticketpb.DeleteTicketResponse, error) {
+	log.Printf("gRPC: Deleting ticket from database - ID: %s", req.Id)
+
+	err := s.repo.Delete(ctx, req.Id, req.ExpectedVersion)
+	if err != nil {
+		log.Printf("gRPC: Error deleting ticket from database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Ticket deleted successfully from database - ID: %s", req.Id)
+
+	return &ticketpb.DeleteTicketResponse{Success: true}, nil
+}
+
+// GetTicketHistory returns the recorded changes of a ticket, oldest first
+func (s *Server) GetTicketHistory(ctx context.Context, req *ticketpb.GetTicketHistoryRequest) (*ticketpb.GetTicketHistoryResponse, error) {
+	log.Printf("gRPC: Getting ticket history from database - ID: %s", req.Id)
+
+	limit := int(req.PageSize)
+	if limit <= 0 || limit > 100 {
+		limit = 50 // Default limit
+	}
+
+	scope := "history/" + req.Id
+	cursor, err := s.pageTokens.decode(req.PageToken, scope)
+	if err != nil {
+		return nil, ToStatus(invalidField("page_token", err.Error()), req.Id)
+	}
+
+	var afterID int64
+	if cursor != nil {
+		afterID, err = strconv.ParseInt(cursor.ID, 10, 64)
+		if err != nil {
+			return nil, ToStatus(invalidField("page_token", err.Error()), req.Id)
+		}
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	events, err := s.repo.History(ctx, req.Id, limit+1, afterID)
+	if err != nil {
+		log.Printf("gRPC: Error getting ticket history from database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	if len(events) == 0 && cursor == nil {
+		return nil, ToStatus(database.ErrNotFound, req.Id)
+	}
+
+	nextPageToken := ""
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[len(events)-1]
+		nextPageToken, err = s.pageTokens.encode(database.ListCursor{ID: strconv.FormatInt(last.ID, 10)}, scope)
+		if err != nil {
+			return nil, ToStatus(err, req.Id)
+		}
+	}
+
+	protoEvents := make([]*ticketpb.TicketEvent, len(events))
+	for i, event := range events {
+		protoEvents[i] = dbEventToProto(event)
+	}
+
+	log.Printf("gRPC: Retrieved %d history events for ticket %s", len(events), req.Id)
+
+	return &ticketpb.GetTicketHistoryResponse{
+		Events:        protoEvents,
+		NextPageToken: nextPageToken,
+	}, nil
+}
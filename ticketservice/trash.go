@@ -0,0 +1,77 @@
+package ticketservice
+
+import (
+	"context"
+	"log"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+)
+
+// deletedPageScope binds ListDeletedTickets page tokens to that RPC
+const deletedPageScope = "deleted"
+
+// RestoreTicket takes a ticket out of the trash
+func (s *Server) RestoreTicket(ctx context.Context, req *ticketpb.RestoreTicketRequest) (*ticketpb.RestoreTicketResponse, error) {
+	log.Printf("gRPC: Restoring ticket in database - ID: %s", req.Id)
+
+	ticket, err := s.repo.Restore(ctx, req.Id, req.ExpectedVersion)
+	if err != nil {
+		log.Printf("gRPC: Error restoring ticket in database: %v", err)
+		return nil, ToStatus(err, req.Id)
+	}
+
+	log.Printf("gRPC: Ticket restored successfully in database - ID: %s", req.Id)
+
+	return &ticketpb.RestoreTicketResponse{
+		Ticket: dbTicketToProto(ticket),
+	}, nil
+}
+
+// ListDeletedTickets lists the tickets in the trash, most recently deleted first
+func (s *Server) ListDeletedTickets(ctx context.Context, req *ticketpb.ListDeletedTicketsRequest) (*ticketpb.ListDeletedTicketsResponse, error) {
+	log.Println("gRPC: Listing deleted tickets from database")
+
+	limit := int(req.PageSize)
+	if limit <= 0 || limit > 100 {
+		limit = 50 // Default limit
+	}
+
+	cursor, err := s.pageTokens.decode(req.PageToken, deletedPageScope)
+	if err != nil {
+		return nil, ToStatus(invalidField("page_token", err.Error()), "")
+	}
+
+	query := database.ListQuery{
+		Filter: database.ListFilter{Deleted: true},
+		SortBy: database.SortByDeletedAt,
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	tickets, err := s.repo.List(ctx, query, limit+1, cursor)
+	if err != nil {
+		log.Printf("gRPC: Error listing deleted tickets from database: %v", err)
+		return nil, ToStatus(err, "")
+	}
+
+	nextPageToken := ""
+	if len(tickets) > limit {
+		tickets = tickets[:limit]
+		nextPageToken, err = s.pageTokens.encode(database.CursorFor(tickets[len(tickets)-1]), deletedPageScope)
+		if err != nil {
+			return nil, ToStatus(err, "")
+		}
+	}
+
+	protoTickets := make([]*ticketpb.Ticket, len(tickets))
+	for i, ticket := range tickets {
+		protoTickets[i] = dbTicketToProto(ticket)
+	}
+
+	log.Printf("gRPC: Listed %d deleted tickets from database", len(tickets))
+
+	return &ticketpb.ListDeletedTicketsResponse{
+		Tickets:       protoTickets,
+		NextPageToken: nextPageToken,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package ticketservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ticketpb "gRPC/proto/ticket"
+)
+
+// updatableFields are the UpdateTicketRequest fields that an update_mask may name
+var updatableFields = []string{"title", "description", "status", "priority", "assignee_id", "tags", "resolution_note"}
+
+// ticketUpdates builds the repository update map for an UpdateTicketRequest.
+// With an update_mask every listed field is written, and empty values clear
+// nullable columns. Without one, only non-empty fields are written.
+func ticketUpdates(req *ticketpb.UpdateTicketRequest) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+
+	if req.UpdateMask == nil {
+		if req.Title != "" {
+			updates["title"] = req.Title
+		}
+		if req.Description != "" {
+			updates["description"] = req.Description
+		}
+		if req.Status != ticketpb.TicketStatus_TICKET_STATUS_UNSPECIFIED {
+			updates["status"] = convertStatusFromProto(req.Status)
+		}
+		if req.Priority != ticketpb.TicketPriority_TICKET_PRIORITY_UNSPECIFIED {
+			updates["priority"] = convertPriorityFromProto(req.Priority)
+		}
+		if req.AssigneeId != "" {
+			updates["assignee_id"] = req.AssigneeId
+		}
+		if len(req.Tags) > 0 {
+			updates["tags"] = req.Tags
+		}
+		if req.ResolutionNote != "" {
+			updates["resolution_note"] = req.ResolutionNote
+		}
+		return updates, nil
+	}
+
+	paths := req.UpdateMask.Paths
+	for _, path := range paths {
+		if path == "*" {
+			if len(paths) > 1 {
+				return nil, invalidField("update_mask", `"*" cannot be combined with other paths`)
+			}
+			paths = updatableFields
+			break
+		}
+	}
+
+	for _, path := range paths {
+		switch path {
+		case "title":
+			if req.Title == "" {
+				return nil, invalidField("title", "cannot be cleared")
+			}
+			updates["title"] = req.Title
+		case "description":
+			updates["description"] = nullableString(req.Description)
+		case "status":
+			if req.Status == ticketpb.TicketStatus_TICKET_STATUS_UNSPECIFIED {
+				return nil, invalidField("status", "cannot be cleared")
+			}
+			updates["status"] = convertStatusFromProto(req.Status)
+		case "priority":
+			if req.Priority == ticketpb.TicketPriority_TICKET_PRIORITY_UNSPECIFIED {
+				return nil, invalidField("priority", "cannot be cleared")
+			}
+			updates["priority"] = convertPriorityFromProto(req.Priority)
+		case "assignee_id":
+			updates["assignee_id"] = nullableString(req.AssigneeId)
+		case "tags":
+			updates["tags"] = req.Tags
+		case "resolution_note":
+			updates["resolution_note"] = nullableString(req.ResolutionNote)
+		default:
+			return nil, invalidField("update_mask", fmt.Sprintf("unknown or immutable field %q", path))
+		}
+	}
+
+	return updates, nil
+}
+
+// applyWorkflow validates a requested status change in updates against the
+// workflow and adds the columns the transition maintains. It returns the
+// version the write must be made against, so that the status checked here
+// cannot change underneath the update.
+func (s *Server) applyWorkflow(ctx context.Context, id string, updates map[string]interface{}, expectedVersion int64) (int64, error) {
+	to, ok := updates["status"].(string)
+	if !ok {
+		return expectedVersion, nil
+	}
+
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.workflow.apply(current, to, updates, time.Now()); err != nil {
+		return 0, err
+	}
+
+	if expectedVersion == 0 {
+		expectedVersion = current.Version
+	}
+	return expectedVersion, nil
+}
+
+// nullableString maps an empty string to nil so the column is set to NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,419 @@
+package ticketservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/validation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// watchBufferSize is how many events a slow stream may fall behind before it is dropped
+	watchBufferSize = 256
+	// watchCatchUpBatch is the number of events read per query when replaying from a cursor
+	watchCatchUpBatch = 500
+	// watchCursorScope binds watch cursors so they cannot be used as page tokens
+	watchCursorScope = "watch"
+	// watchRetryInterval is how often the hub checks again while transactions
+	// in flight hold back committed events
+	watchRetryInterval = 250 * time.Millisecond
+)
+
+// watchEvent is a ticket event together with the ticket's state right after it
+type watchEvent struct {
+	event  *database.TicketEvent
+	ticket *database.Ticket // nil once the ticket is deleted
+}
+
+// ticketState is the subset of a ticket that watch filters match against
+type ticketState struct {
+	status   string
+	assignee string
+	tags     []string
+}
+
+// watchFilter selects the events a WatchTickets stream receives
+type watchFilter struct {
+	statuses map[string]bool
+	assignee string
+	tag      string
+}
+
+func watchFilterFromProto(req *ticketpb.WatchTicketsRequest) (watchFilter, error) {
+	f := watchFilter{assignee: req.AssigneeId, tag: req.Tag}
+	for _, st := range req.Statuses {
+		status, err := filterStatusFromProto("statuses", st)
+		if err != nil {
+			return f, err
+		}
+		if f.statuses == nil {
+			f.statuses = make(map[string]bool)
+		}
+		f.statuses[status] = true
+	}
+	return f, nil
+}
+
+// matches reports whether the ticket matched the filter before or after the event,
+// so that watchers see tickets both entering and leaving their view
+func (f watchFilter) matches(ev *watchEvent) bool {
+	before, after := eventStates(ev)
+	return f.matchesState(before) || f.matchesState(after)
+}
+
+func (f watchFilter) matchesState(st ticketState) bool {
+	if st.status == "" {
+		return false // the ticket did not exist on this side of the event
+	}
+	if f.statuses != nil && !f.statuses[st.status] {
+		return false
+	}
+	if f.assignee != "" && st.assignee != f.assignee {
+		return false
+	}
+	if f.tag != "" {
+		for _, t := range st.tags {
+			if t == f.tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// eventStates reconstructs the filterable state of a ticket on either side of
+// an event from its state after the event and the field changes it recorded
+func eventStates(ev *watchEvent) (before, after ticketState) {
+	if ev.ticket != nil {
+		after = ticketState{
+			status:   ev.ticket.Status,
+			assignee: ev.ticket.AssigneeID.String,
+			tags:     ev.ticket.Tags,
+		}
+	}
+
+	before = after
+	for _, change := range ev.event.Changes {
+		value := ""
+		if change.Before != nil {
+			value = *change.Before
+		}
+		switch change.Field {
+		case "status":
+			before.status = value
+		case "assignee_id":
+			before.assignee = value
+		case "tags":
+			before.tags = nil
+			if value != "" {
+				json.Unmarshal([]byte(value), &before.tags)
+			}
+		}
+	}
+
+	return before, after
+}
+
+// watchSubscription is a single WatchTickets stream registered with the hub
+type watchSubscription struct {
+	filter   watchFilter
+	events   chan *watchEvent
+	overflow chan struct{} // closed when the stream fell too far behind
+}
+
+// watchHub fans out ticket events from one LISTEN connection to every WatchTickets stream
+type watchHub struct {
+	repo database.TicketStore
+
+	// catchUp serializes reading events, so that they are dispatched in order
+	catchUp  sync.Mutex
+	position database.EventPosition // of the last event read
+	retry    *time.Timer            // set while events are held back
+
+	mu   sync.Mutex
+	subs map[*watchSubscription]struct{}
+	done chan struct{}
+}
+
+// newWatchHub creates a hub that dispatches the events committed from now on
+func newWatchHub(ctx context.Context, repo database.TicketStore) (*watchHub, error) {
+	position, err := repo.EventHorizon(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &watchHub{
+		repo:     repo,
+		position: position,
+		subs:     make(map[*watchSubscription]struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (h *watchHub) subscribe(filter watchFilter) *watchSubscription {
+	sub := &watchSubscription{
+		filter:   filter,
+		events:   make(chan *watchEvent, watchBufferSize),
+		overflow: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+func (h *watchHub) unsubscribe(sub *watchSubscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// close ends every stream so that the server can shut down gracefully
+func (h *watchHub) close() {
+	h.catchUp.Lock()
+	defer h.catchUp.Unlock()
+
+	if h.retry != nil {
+		h.retry.Stop()
+	}
+	close(h.done)
+}
+
+// handle is called by the event listener for each committed event, and after
+// a reconnect. Either way it dispatches every event that became final since
+// the last call, so nothing is missed and nothing is sent twice.
+func (h *watchHub) handle(ctx context.Context) {
+	h.catchUp.Lock()
+	defer h.catchUp.Unlock()
+
+	select {
+	case <-h.done:
+		return
+	default:
+	}
+
+	for {
+		events, held, err := finalEventsAfter(ctx, h.repo, h.position, watchCatchUpBatch)
+		if err != nil {
+			log.Printf("gRPC: Error reading ticket events: %v", err)
+			return
+		}
+		for _, event := range events {
+			h.dispatch(ctx, event)
+			h.position = event.Position()
+		}
+		if held {
+			// The transactions holding them back may end without notifying
+			if h.retry == nil {
+				h.retry = time.AfterFunc(watchRetryInterval, func() {
+					h.catchUp.Lock()
+					h.retry = nil
+					h.catchUp.Unlock()
+					h.handle(ctx)
+				})
+			}
+			return
+		}
+		if len(events) < watchCatchUpBatch {
+			return
+		}
+	}
+}
+
+func (h *watchHub) dispatch(ctx context.Context, event *database.TicketEvent) {
+	ev, err := loadWatchEvent(ctx, h.repo, event)
+	if err != nil {
+		log.Printf("gRPC: Error loading ticket for event %d: %v", event.ID, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if !sub.filter.matches(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			close(sub.overflow)
+			delete(h.subs, sub)
+		}
+	}
+}
+
+// finalEventsAfter returns the events positioned after the given position
+// that no later commit can precede, in order. held reports that the events
+// following them are held back by transactions in flight.
+func finalEventsAfter(ctx context.Context, repo database.TicketStore, after database.EventPosition, limit int) (events []*database.TicketEvent, held bool, err error) {
+	// Read the horizon first, so that every event up to it is visible below
+	horizon, err := repo.EventHorizon(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	events, err = repo.EventsAfter(ctx, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	for i, event := range events {
+		if horizon.Before(event.Position()) {
+			return events[:i], true, nil
+		}
+	}
+	return events, false, nil
+}
+
+// encodeWatchCursor turns an event position into a resume cursor
+func (s *Server) encodeWatchCursor(position database.EventPosition) (string, error) {
+	id := strconv.FormatInt(position.ID, 10)
+	if position.TxID != 0 {
+		id = strconv.FormatInt(position.TxID, 10) + ":" + id
+	}
+	return s.pageTokens.encode(database.ListCursor{ID: id}, watchCursorScope)
+}
+
+// decodeWatchCursor returns the event position of a resume cursor. Cursors
+// without a transaction ID were issued before events were ordered by it.
+func (s *Server) decodeWatchCursor(token string) (database.EventPosition, error) {
+	cursor, err := s.pageTokens.decode(token, watchCursorScope)
+	if err != nil {
+		return database.EventPosition{}, err
+	}
+
+	txID, id, ok := strings.Cut(cursor.ID, ":")
+	if !ok {
+		txID, id = "0", cursor.ID
+	}
+
+	var position database.EventPosition
+	if position.TxID, err = strconv.ParseInt(txID, 10, 64); err != nil {
+		return database.EventPosition{}, errInvalidPageToken
+	}
+	if position.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return database.EventPosition{}, errInvalidPageToken
+	}
+	return position, nil
+}
+
+// loadWatchEvent attaches the state of the event's ticket right after the
+// event. Events recorded before that state was kept fall back to the current one.
+func loadWatchEvent(ctx context.Context, repo database.TicketStore, event *database.TicketEvent) (*watchEvent, error) {
+	if event.Ticket != nil || event.Type == database.EventDeleted || event.Type == database.EventPurged {
+		return &watchEvent{event: event, ticket: event.Ticket}, nil
+	}
+
+	ticket, err := repo.GetByID(ctx, event.TicketID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, err
+	}
+	return &watchEvent{event: event, ticket: ticket}, nil
+}
+
+// WatchTickets streams ticket changes as they are committed
+func (s *Server) WatchTickets(req *ticketpb.WatchTicketsRequest, stream grpc.ServerStreamingServer[ticketpb.WatchTicketsResponse]) error {
+	log.Println("gRPC: Watching tickets")
+
+	if err := validation.Validate(req); err != nil {
+		return ToStatus(err, "")
+	}
+
+	ctx := stream.Context()
+	filter, err := watchFilterFromProto(req)
+	if err != nil {
+		return ToStatus(err, "")
+	}
+
+	var after database.EventPosition
+	if req.ResumeCursor != "" {
+		if after, err = s.decodeWatchCursor(req.ResumeCursor); err != nil {
+			return ToStatus(invalidField("resume_cursor", err.Error()), "")
+		}
+	}
+
+	// Subscribe before replaying so nothing committed in between is lost
+	sub := s.watch.subscribe(filter)
+	defer s.watch.unsubscribe(sub)
+
+	// Headers tell the client that every later change will be delivered
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	send := func(ev *watchEvent) error {
+		cursor, err := s.encodeWatchCursor(ev.event.Position())
+		if err != nil {
+			return ToStatus(err, "")
+		}
+		resp := &ticketpb.WatchTicketsResponse{
+			Event:  dbEventToProto(ev.event),
+			Cursor: cursor,
+		}
+		if ev.ticket != nil {
+			resp.Ticket = dbTicketToProto(ev.ticket)
+		}
+		return stream.Send(resp)
+	}
+
+	// The replay stops at events held back by transactions in flight, which
+	// the hub dispatches once they are final. Both go in position order, so
+	// live events up to the last one replayed are duplicates.
+	replayed := after
+	if req.ResumeCursor != "" {
+		for {
+			events, held, err := finalEventsAfter(ctx, s.repo, replayed, watchCatchUpBatch)
+			if err != nil {
+				return ToStatus(err, "")
+			}
+			for _, event := range events {
+				replayed = event.Position()
+
+				ev, err := loadWatchEvent(ctx, s.repo, event)
+				if err != nil {
+					return ToStatus(err, event.TicketID)
+				}
+				if !filter.matches(ev) {
+					continue
+				}
+				if err := send(ev); err != nil {
+					return err
+				}
+			}
+			if held || len(events) < watchCatchUpBatch {
+				break
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.watch.done:
+			return status.Error(codes.Unavailable, "server is shutting down; resume with the last cursor")
+		case <-sub.overflow:
+			return status.Error(codes.ResourceExhausted, "stream fell behind; resume with the last cursor")
+		case ev := <-sub.events:
+			if !replayed.Before(ev.event.Position()) {
+				continue
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
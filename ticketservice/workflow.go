@@ -0,0 +1,176 @@
+package ticketservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gRPC/database"
+)
+
+// workflowFields are the ticket fields a transition may require to be set
+var workflowFields = map[string]bool{
+	"resolution_note": true,
+	"assignee_id":     true,
+	"description":     true,
+}
+
+// workflowTimestamps are the columns a transition may stamp or clear
+var workflowTimestamps = map[string]bool{
+	"resolved_at": true,
+	"closed_at":   true,
+}
+
+// workflowStatuses are the statuses a workflow may refer to
+var workflowStatuses = map[string]bool{
+	"OPEN":        true,
+	"IN_PROGRESS": true,
+	"RESOLVED":    true,
+	"CLOSED":      true,
+}
+
+// workflowTransition is one allowed status change
+type workflowTransition struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Require []string `json:"require,omitempty"` // fields that must be non-empty after the transition
+	Set     []string `json:"set,omitempty"`     // timestamps set to the transition time
+	Clear   []string `json:"clear,omitempty"`   // timestamps reset to NULL
+}
+
+// workflowConfig is the on-disk representation of a workflow
+type workflowConfig struct {
+	Initial     string               `json:"initial"`
+	Transitions []workflowTransition `json:"transitions"`
+}
+
+// defaultWorkflowConfig is used when WORKFLOW_CONFIG is not set
+var defaultWorkflowConfig = workflowConfig{
+	Initial: "OPEN",
+	Transitions: []workflowTransition{
+		{From: "OPEN", To: "IN_PROGRESS"},
+		{From: "OPEN", To: "CLOSED", Require: []string{"resolution_note"}, Set: []string{"closed_at"}},
+		{From: "IN_PROGRESS", To: "OPEN"},
+		{From: "IN_PROGRESS", To: "RESOLVED", Require: []string{"resolution_note"}, Set: []string{"resolved_at"}},
+		{From: "RESOLVED", To: "IN_PROGRESS", Clear: []string{"resolved_at"}},
+		{From: "RESOLVED", To: "CLOSED", Set: []string{"closed_at"}},
+		{From: "CLOSED", To: "IN_PROGRESS", Clear: []string{"resolved_at", "closed_at"}},
+	},
+}
+
+// workflow enforces the allowed status transitions of a ticket
+type workflow struct {
+	initial     string
+	transitions map[[2]string]workflowTransition
+}
+
+// transitionError reports a status change the workflow does not allow
+type transitionError struct {
+	from string
+	to   string
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("cannot move ticket from %s to %s", e.from, e.to)
+}
+
+// newWorkflow validates a workflow configuration
+func newWorkflow(cfg workflowConfig) (*workflow, error) {
+	if !workflowStatuses[cfg.Initial] {
+		return nil, fmt.Errorf("unknown initial status %q", cfg.Initial)
+	}
+
+	w := &workflow{initial: cfg.Initial, transitions: make(map[[2]string]workflowTransition)}
+	for _, t := range cfg.Transitions {
+		if !workflowStatuses[t.From] || !workflowStatuses[t.To] {
+			return nil, fmt.Errorf("transition %s -> %s: unknown status", t.From, t.To)
+		}
+		if t.From == t.To {
+			return nil, fmt.Errorf("transition %s -> %s: status must change", t.From, t.To)
+		}
+		for _, f := range t.Require {
+			if !workflowFields[f] {
+				return nil, fmt.Errorf("transition %s -> %s: unknown required field %q", t.From, t.To, f)
+			}
+		}
+		for _, ts := range append(append([]string{}, t.Set...), t.Clear...) {
+			if !workflowTimestamps[ts] {
+				return nil, fmt.Errorf("transition %s -> %s: unknown timestamp %q", t.From, t.To, ts)
+			}
+		}
+		w.transitions[[2]string{t.From, t.To}] = t
+	}
+
+	return w, nil
+}
+
+// loadWorkflow reads a JSON workflow file, or returns the default workflow if path is empty
+func loadWorkflow(path string) (*workflow, error) {
+	if path == "" {
+		return newWorkflow(defaultWorkflowConfig)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow config: %w", err)
+	}
+
+	var cfg workflowConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow config: %w", err)
+	}
+
+	return newWorkflow(cfg)
+}
+
+// apply checks a status change against the workflow and adds the resulting
+// column changes to updates. updates holds the changes already requested, so
+// required fields may be satisfied either by the ticket or by the request.
+// Moving to the ticket's current status is a no-op.
+func (w *workflow) apply(ticket *database.Ticket, to string, updates map[string]interface{}, now time.Time) error {
+	if ticket.Status == to {
+		delete(updates, "status")
+		return nil
+	}
+
+	t, ok := w.transitions[[2]string{ticket.Status, to}]
+	if !ok {
+		return &transitionError{from: ticket.Status, to: to}
+	}
+
+	for _, field := range t.Require {
+		if !hasValue(ticket, field, updates) {
+			return invalidField(field, fmt.Sprintf("is required when moving a ticket to %s", to))
+		}
+	}
+
+	updates["status"] = to
+	for _, ts := range t.Set {
+		updates[ts] = now
+	}
+	for _, ts := range t.Clear {
+		updates[ts] = nil
+	}
+
+	return nil
+}
+
+// hasValue reports whether a field will be non-empty once updates are applied
+func hasValue(ticket *database.Ticket, field string, updates map[string]interface{}) bool {
+	if v, ok := updates[field]; ok {
+		s, _ := v.(string)
+		return s != ""
+	}
+
+	switch field {
+	case "resolution_note":
+		return ticket.ResolutionNote.Valid && ticket.ResolutionNote.String != ""
+	case "assignee_id":
+		return ticket.AssigneeID.Valid && ticket.AssigneeID.String != ""
+	case "description":
+		return ticket.Description.Valid && ticket.Description.String != ""
+	default:
+		return false
+	}
+}
@@ -0,0 +1,465 @@
+package tickettest_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/ticketservice"
+	"gRPC/tickettest"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenActor is the actor every recorded call is made as
+const goldenActor = "golden-tester"
+
+// goldenTests make calls against a freshly seeded server. Every call and its
+// outcome is compared with testdata/<name>.golden.
+var goldenTests = []struct {
+	name string
+	run  func(r *recorder)
+}{
+	{"CreateTicket", func(r *recorder) {
+		unary(r, "CreateTicket", r.client.CreateTicket, &ticketpb.CreateTicketRequest{
+			Title:       "Password reset email never arrives",
+			Description: "Reset emails are not delivered to addresses on the corporate domain",
+			Priority:    ticketpb.TicketPriority_TICKET_PRIORITY_MEDIUM,
+			AssigneeId:  "alice",
+			Tags:        []string{"auth", "email"},
+			ReporterId:  "reporter-2",
+		})
+		unary(r, "CreateTicket", r.client.CreateTicket, &ticketpb.CreateTicketRequest{
+			Description: "Missing a title and a reporter",
+			Tags:        []string{"dup", "dup"},
+		})
+	}},
+	{"BulkCreateTickets", func(r *recorder) {
+		r.bulkCreate(
+			&ticketpb.CreateTicketRequest{Title: "Export fails for large projects", ReporterId: "reporter-2"},
+			&ticketpb.CreateTicketRequest{Title: "", ReporterId: "reporter-2"},
+			&ticketpb.CreateTicketRequest{Title: "Typo on the pricing page", Priority: ticketpb.TicketPriority_TICKET_PRIORITY_LOW, Tags: []string{"ui"}, ReporterId: "reporter-3"},
+		)
+	}},
+	{"GetTicket", func(r *recorder) {
+		unary(r, "GetTicket", r.client.GetTicket, &ticketpb.GetTicketRequest{Id: "ticket-1"})
+		unary(r, "GetTicket", r.client.GetTicket, &ticketpb.GetTicketRequest{Id: "ticket-404"})
+	}},
+	{"ListTickets", func(r *recorder) {
+		first := unary(r, "ListTickets", r.client.ListTickets, &ticketpb.ListTicketsRequest{
+			PageSize: 2,
+			SortBy:   ticketpb.TicketSortField_TICKET_SORT_FIELD_PRIORITY,
+		})
+		unary(r, "ListTickets", r.client.ListTickets, &ticketpb.ListTicketsRequest{
+			PageSize:  2,
+			PageToken: first.GetNextPageToken(),
+			SortBy:    ticketpb.TicketSortField_TICKET_SORT_FIELD_PRIORITY,
+		})
+		unary(r, "ListTickets", r.client.ListTickets, &ticketpb.ListTicketsRequest{
+			Filter:        &ticketpb.TicketFilter{Tags: []string{"bug"}},
+			SortDirection: ticketpb.SortDirection_SORT_DIRECTION_ASC,
+		})
+		unary(r, "ListTickets", r.client.ListTickets, &ticketpb.ListTicketsRequest{PageToken: "not-a-token"})
+		unary(r, "ListTickets", r.client.ListTickets, &ticketpb.ListTicketsRequest{
+			Filter: &ticketpb.TicketFilter{Statuses: []ticketpb.TicketStatus{99}},
+		})
+		unary(r, "ListTickets", r.client.ListTickets, &ticketpb.ListTicketsRequest{
+			Filter: &ticketpb.TicketFilter{Priorities: []ticketpb.TicketPriority{99}},
+		})
+	}},
+	{"SearchTickets", func(r *recorder) {
+		unary(r, "SearchTickets", r.client.SearchTickets, &ticketpb.SearchTicketsRequest{Query: "checkout"})
+		unary(r, "SearchTickets", r.client.SearchTickets, &ticketpb.SearchTicketsRequest{
+			Query:  "error or dashboard",
+			Filter: &ticketpb.TicketFilter{Statuses: []ticketpb.TicketStatus{ticketpb.TicketStatus_TICKET_STATUS_OPEN}},
+		})
+		unary(r, "SearchTickets", r.client.SearchTickets, &ticketpb.SearchTicketsRequest{})
+	}},
+	{"UpdateTicket", func(r *recorder) {
+		unary(r, "UpdateTicket", r.client.UpdateTicket, &ticketpb.UpdateTicketRequest{
+			Id:              "ticket-1",
+			Title:           "Login fails with SSO on Safari",
+			Priority:        ticketpb.TicketPriority_TICKET_PRIORITY_CRITICAL,
+			UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"title", "priority", "assignee_id"}},
+			ExpectedVersion: 1,
+		})
+		unary(r, "UpdateTicket", r.client.UpdateTicket, &ticketpb.UpdateTicketRequest{
+			Id:              "ticket-1",
+			Title:           "Stale edit",
+			ExpectedVersion: 1,
+		})
+		unary(r, "UpdateTicket", r.client.UpdateTicket, &ticketpb.UpdateTicketRequest{
+			Id:         "ticket-1",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"reporter_id"}},
+		})
+	}},
+	{"BatchGetTickets", func(r *recorder) {
+		unary(r, "BatchGetTickets", r.client.BatchGetTickets, &ticketpb.BatchGetTicketsRequest{
+			Ids:  []string{"ticket-3", "ticket-404", "ticket-1"},
+			Mode: ticketpb.BatchMode_BATCH_MODE_BEST_EFFORT,
+		})
+		unary(r, "BatchGetTickets", r.client.BatchGetTickets, &ticketpb.BatchGetTicketsRequest{
+			Ids: []string{"ticket-3", "ticket-404"},
+		})
+	}},
+	{"BatchUpdateTickets", func(r *recorder) {
+		unary(r, "BatchUpdateTickets", r.client.BatchUpdateTickets, &ticketpb.BatchUpdateTicketsRequest{
+			Filter:     &ticketpb.TicketFilter{Tags: []string{"bug"}},
+			AssigneeId: "bob",
+		})
+		unary(r, "BatchUpdateTickets", r.client.BatchUpdateTickets, &ticketpb.BatchUpdateTicketsRequest{
+			Ids:    []string{"ticket-1", "ticket-404"},
+			Status: ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS,
+			Mode:   ticketpb.BatchMode_BATCH_MODE_BEST_EFFORT,
+		})
+	}},
+	{"TransitionTicket", func(r *recorder) {
+		unary(r, "TransitionTicket", r.client.TransitionTicket, &ticketpb.TransitionTicketRequest{
+			Id:     "ticket-1",
+			Status: ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS,
+		})
+		unary(r, "TransitionTicket", r.client.TransitionTicket, &ticketpb.TransitionTicketRequest{
+			Id:     "ticket-2",
+			Status: ticketpb.TicketStatus_TICKET_STATUS_RESOLVED,
+		})
+		unary(r, "TransitionTicket", r.client.TransitionTicket, &ticketpb.TransitionTicketRequest{
+			Id:             "ticket-2",
+			Status:         ticketpb.TicketStatus_TICKET_STATUS_RESOLVED,
+			ResolutionNote: "Shipped behind the dark_mode flag",
+		})
+		unary(r, "TransitionTicket", r.client.TransitionTicket, &ticketpb.TransitionTicketRequest{
+			Id:     "ticket-3",
+			Status: ticketpb.TicketStatus_TICKET_STATUS_RESOLVED,
+		})
+	}},
+	{"DeleteTicket", func(r *recorder) {
+		unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-2", ExpectedVersion: 1})
+		unary(r, "GetTicket", r.client.GetTicket, &ticketpb.GetTicketRequest{Id: "ticket-2"})
+		unary(r, "GetTicket", r.client.GetTicket, &ticketpb.GetTicketRequest{Id: "ticket-2", IncludeDeleted: true})
+		unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-2"})
+		unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-3", ExpectedVersion: 7})
+	}},
+	{"RestoreTicket", func(r *recorder) {
+		unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-2"})
+		unary(r, "RestoreTicket", r.client.RestoreTicket, &ticketpb.RestoreTicketRequest{Id: "ticket-2"})
+		unary(r, "RestoreTicket", r.client.RestoreTicket, &ticketpb.RestoreTicketRequest{Id: "ticket-1"})
+	}},
+	{"ListDeletedTickets", func(r *recorder) {
+		unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-1"})
+		unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-3"})
+		unary(r, "ListDeletedTickets", r.client.ListDeletedTickets, &ticketpb.ListDeletedTicketsRequest{PageSize: 1})
+	}},
+	{"GetTicketHistory", func(r *recorder) {
+		unary(r, "UpdateTicket", r.client.UpdateTicket, &ticketpb.UpdateTicketRequest{
+			Id:   "ticket-1",
+			Tags: []string{"auth", "bug", "sso"},
+		})
+		unary(r, "GetTicketHistory", r.client.GetTicketHistory, &ticketpb.GetTicketHistoryRequest{Id: "ticket-1"})
+		unary(r, "GetTicketHistory", r.client.GetTicketHistory, &ticketpb.GetTicketHistoryRequest{Id: "ticket-404"})
+	}},
+	{"WatchTickets", func(r *recorder) {
+		r.watch(&ticketpb.WatchTicketsRequest{
+			Statuses: []ticketpb.TicketStatus{ticketpb.TicketStatus_TICKET_STATUS_OPEN},
+		}, 2, func() {
+			unary(r, "UpdateTicket", r.client.UpdateTicket, &ticketpb.UpdateTicketRequest{Id: "ticket-1", AssigneeId: "bob"})
+			// ticket-2 is in progress, so its deletion is filtered out
+			unary(r, "DeleteTicket", r.client.DeleteTicket, &ticketpb.DeleteTicketRequest{Id: "ticket-2"})
+			unary(r, "TransitionTicket", r.client.TransitionTicket, &ticketpb.TransitionTicketRequest{
+				Id:     "ticket-3",
+				Status: ticketpb.TicketStatus_TICKET_STATUS_IN_PROGRESS,
+			})
+		})
+	}},
+	{"AddComment", func(r *recorder) {
+		unary(r, "AddComment", r.client.AddComment, &ticketpb.AddCommentRequest{
+			TicketId: "ticket-3",
+			AuthorId: "alice",
+			Body:     "Reproduced with **50** concurrent checkouts",
+		})
+		unary(r, "AddComment", r.client.AddComment, &ticketpb.AddCommentRequest{TicketId: "ticket-404", AuthorId: "alice", Body: "Hello?"})
+		unary(r, "AddComment", r.client.AddComment, &ticketpb.AddCommentRequest{TicketId: "ticket-3"})
+	}},
+	{"ListComments", func(r *recorder) {
+		unary(r, "AddComment", r.client.AddComment, &ticketpb.AddCommentRequest{TicketId: "ticket-1", AuthorId: "bob", Body: "Same here since Monday"})
+		unary(r, "ListComments", r.client.ListComments, &ticketpb.ListCommentsRequest{TicketId: "ticket-1"})
+		unary(r, "ListComments", r.client.ListComments, &ticketpb.ListCommentsRequest{TicketId: "ticket-404"})
+	}},
+	{"EditComment", func(r *recorder) {
+		unary(r, "EditComment", r.client.EditComment, &ticketpb.EditCommentRequest{Id: "comment-1", Body: "Only affects the staging SSO provider"})
+		unary(r, "EditComment", r.client.EditComment, &ticketpb.EditCommentRequest{Id: "comment-404", Body: "Hello?"})
+	}},
+	{"DeleteComment", func(r *recorder) {
+		unary(r, "DeleteComment", r.client.DeleteComment, &ticketpb.DeleteCommentRequest{Id: "comment-1"})
+		unary(r, "ListComments", r.client.ListComments, &ticketpb.ListCommentsRequest{TicketId: "ticket-1"})
+		unary(r, "DeleteComment", r.client.DeleteComment, &ticketpb.DeleteCommentRequest{Id: "comment-1"})
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, tt := range goldenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+			s := tickettest.NewServer(t, tickettest.Config{
+				Service: ticketservice.Config{PageTokenSecret: "golden"},
+			})
+			seed(t, s)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r := &recorder{
+				t:      t,
+				ctx:    metadata.AppendToOutgoingContext(ctx, ticketservice.ActorMetadataKey, goldenActor),
+				client: s.Client,
+			}
+			tt.run(r)
+			r.check(filepath.Join("testdata", tt.name+".golden"), start)
+		})
+	}
+}
+
+// seedTime is when the seeded tickets were created, long before any test runs
+var seedTime = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// seed stores three tickets and a comment with fixed IDs and timestamps
+func seed(t *testing.T, s *tickettest.Server) {
+	t.Helper()
+	ctx := database.WithActor(context.Background(), "seed")
+
+	tickets := []*database.Ticket{
+		{
+			ID:          "ticket-1",
+			Title:       "Login fails with SSO",
+			Description: sql.NullString{String: "Users signing in through the SSO provider get an error page", Valid: true},
+			Status:      "OPEN",
+			Priority:    "HIGH",
+			AssigneeID:  sql.NullString{String: "alice", Valid: true},
+			Tags:        []string{"auth", "bug"},
+			ReporterID:  "reporter-1",
+		},
+		{
+			ID:         "ticket-2",
+			Title:      "Dark mode for the dashboard",
+			Status:     "IN_PROGRESS",
+			Priority:   "LOW",
+			Tags:       []string{"ui"},
+			ReporterID: "reporter-2",
+		},
+		{
+			ID:          "ticket-3",
+			Title:       "Checkout times out under load",
+			Description: sql.NullString{String: "The payment provider call exceeds the checkout deadline", Valid: true},
+			Status:      "OPEN",
+			Priority:    "CRITICAL",
+			Tags:        []string{"bug", "payments"},
+			ReporterID:  "reporter-1",
+		},
+	}
+	for i, ticket := range tickets {
+		ticket.CreatedAt = seedTime.Add(time.Duration(i) * time.Hour)
+		ticket.UpdatedAt = ticket.CreatedAt
+		if _, err := s.Tickets.Create(ctx, ticket); err != nil {
+			t.Fatalf("failed to seed %s: %v", ticket.ID, err)
+		}
+	}
+
+	comment := &database.Comment{
+		ID:        "comment-1",
+		TicketID:  "ticket-1",
+		AuthorID:  "reporter-1",
+		Body:      "Happens with *every* SSO account",
+		CreatedAt: seedTime.Add(24 * time.Hour),
+	}
+	if _, err := s.Comments.Create(ctx, comment); err != nil {
+		t.Fatalf("failed to seed %s: %v", comment.ID, err)
+	}
+}
+
+// exchange is one recorded call. Streaming calls fill Requests or Responses.
+type exchange struct {
+	Call      string            `json:"call"`
+	Request   json.RawMessage   `json:"request,omitempty"`
+	Requests  []json.RawMessage `json:"requests,omitempty"`
+	Response  json.RawMessage   `json:"response,omitempty"`
+	Responses []json.RawMessage `json:"responses,omitempty"`
+	Error     json.RawMessage   `json:"error,omitempty"`
+}
+
+// recorder makes calls as goldenActor and records them
+type recorder struct {
+	t         *testing.T
+	ctx       context.Context
+	client    ticketpb.TicketServiceClient
+	exchanges []exchange
+}
+
+// unary records a unary call and returns its response, nil if it failed
+func unary[Req, Resp proto.Message](r *recorder, name string, call func(context.Context, Req, ...grpc.CallOption) (Resp, error), req Req) Resp {
+	r.t.Helper()
+	e := exchange{Call: name, Request: r.marshal(req)}
+	resp, err := call(r.ctx, req)
+	if err != nil {
+		e.Error = r.marshalStatus(err)
+	} else {
+		e.Response = r.marshal(resp)
+	}
+	r.exchanges = append(r.exchanges, e)
+	return resp
+}
+
+// bulkCreate records a BulkCreateTickets stream of reqs
+func (r *recorder) bulkCreate(reqs ...*ticketpb.CreateTicketRequest) {
+	r.t.Helper()
+	e := exchange{Call: "BulkCreateTickets"}
+	stream, err := r.client.BulkCreateTickets(r.ctx)
+	if err != nil {
+		r.t.Fatalf("failed to open BulkCreateTickets: %v", err)
+	}
+	for _, req := range reqs {
+		e.Requests = append(e.Requests, r.marshal(req))
+		if err := stream.Send(req); err != nil {
+			r.t.Fatalf("failed to send to BulkCreateTickets: %v", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		e.Error = r.marshalStatus(err)
+	} else {
+		e.Response = r.marshal(resp)
+	}
+	r.exchanges = append(r.exchanges, e)
+}
+
+// watch records the first count messages of a WatchTickets stream, opened
+// before changes runs
+func (r *recorder) watch(req *ticketpb.WatchTicketsRequest, count int, changes func()) {
+	r.t.Helper()
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+
+	stream, err := r.client.WatchTickets(ctx, req)
+	if err != nil {
+		r.t.Fatalf("failed to open WatchTickets: %v", err)
+	}
+	// The server sends headers once it is subscribed
+	if _, err := stream.Header(); err != nil {
+		r.t.Fatalf("WatchTickets failed: %v", err)
+	}
+	changes()
+
+	e := exchange{Call: "WatchTickets", Request: r.marshal(req)}
+	for len(e.Responses) < count {
+		resp, err := stream.Recv()
+		if err != nil {
+			r.t.Fatalf("WatchTickets failed after %d messages: %v", len(e.Responses), err)
+		}
+		e.Responses = append(e.Responses, r.marshal(resp))
+	}
+	r.exchanges = append(r.exchanges, e)
+}
+
+func (r *recorder) marshal(m proto.Message) json.RawMessage {
+	r.t.Helper()
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		r.t.Fatalf("failed to marshal %T: %v", m, err)
+	}
+	return b
+}
+
+// marshalStatus encodes the gRPC status of err with its code by name
+func (r *recorder) marshalStatus(err error) json.RawMessage {
+	r.t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		r.t.Fatalf("call failed without a gRPC status: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(r.marshal(st.Proto()), &fields); err != nil {
+		r.t.Fatalf("failed to decode status: %v", err)
+	}
+	fields["code"], _ = json.Marshal(st.Code().String())
+	b, err := json.Marshal(fields)
+	if err != nil {
+		r.t.Fatalf("failed to encode status: %v", err)
+	}
+	return b
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	timestampPattern = regexp.MustCompile(`"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z"`)
+	tokenPattern     = regexp.MustCompile(`"(nextPageToken|pageToken|cursor|resumeCursor)": "[A-Za-z0-9_.=-]{20,}"`)
+)
+
+// scrub replaces the values that differ between runs: generated IDs become
+// numbered placeholders, times after start become <now>, and page tokens and
+// cursors, which embed both, become <token>
+func scrub(b []byte, start time.Time) []byte {
+	uuids := make(map[string]string)
+	b = uuidPattern.ReplaceAllFunc(b, func(id []byte) []byte {
+		placeholder, ok := uuids[string(id)]
+		if !ok {
+			placeholder = fmt.Sprintf("<uuid-%d>", len(uuids)+1)
+			uuids[string(id)] = placeholder
+		}
+		return []byte(placeholder)
+	})
+	b = timestampPattern.ReplaceAllFunc(b, func(ts []byte) []byte {
+		parsed, err := time.Parse(time.RFC3339Nano, string(ts[1:len(ts)-1]))
+		if err != nil || parsed.Before(start) {
+			return ts
+		}
+		return []byte(`"<now>"`)
+	})
+	return tokenPattern.ReplaceAll(b, []byte(`"$1": "<token>"`))
+}
+
+// check compares the recorded calls with the golden file at path, or
+// rewrites it with -update
+func (r *recorder) check(path string, start time.Time) {
+	r.t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r.exchanges); err != nil {
+		r.t.Fatalf("failed to encode calls: %v", err)
+	}
+	got := scrub(buf.Bytes(), start)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		r.t.Errorf("calls differ from %s, run with -update if the change is intended\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,148 @@
+// Package tickettest runs the ticket service in process over an in-memory
+// connection, so that code calling it can be tested without a network,
+// a database or Docker
+package tickettest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/ticketservice"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// bufferSize is the capacity of the in-memory connection in each direction
+const bufferSize = 1 << 20
+
+// Config selects the stores and settings of a test server. The zero value
+// serves an empty in-memory store.
+type Config struct {
+	// Tickets and Comments replace the in-memory store and must be set
+	// together. WatchTickets streams only see the changes announced by Events,
+	// which the server runs but does not close.
+	Tickets  database.TicketStore
+	Comments database.CommentStore
+	Events   database.EventSource
+
+	// Service configures the ticket service itself
+	Service ticketservice.Config
+}
+
+// Server is a ticket service served in process, without authentication.
+// Writes are attributed to the actor named in the ticketservice.ActorMetadataKey
+// metadata of a call, like on a server started with AUTH_DISABLED=true.
+type Server struct {
+	// Client is connected to the server
+	Client ticketpb.TicketServiceClient
+	// Conn is the connection of Client, for other clients of the same server
+	Conn *grpc.ClientConn
+
+	// Tickets and Comments are the stores behind the server, for seeding
+	// data and inspecting the effect of calls
+	Tickets  database.TicketStore
+	Comments database.CommentStore
+
+	service    *ticketservice.Server
+	grpcServer *grpc.Server
+	listener   *bufconn.Listener
+	stopEvents context.CancelFunc
+	events     database.EventSource // closed with the server, if created by it
+}
+
+// Start serves a ticket service on the stores of config and connects a client
+// to it. The server runs until Close is called.
+func Start(config Config) (*Server, error) {
+	if (config.Tickets == nil) != (config.Comments == nil) {
+		return nil, errors.New("tickettest: Tickets and Comments must be set together")
+	}
+
+	s := &Server{
+		Tickets:  config.Tickets,
+		Comments: config.Comments,
+	}
+	events := config.Events
+	if config.Tickets == nil {
+		store := database.NewMemoryStore()
+		listener := store.NewEventListener()
+		s.Tickets, s.Comments, s.events = store, store.Comments(), listener
+		events = listener
+	}
+
+	service, err := ticketservice.NewServer(context.Background(), s.Tickets, s.Comments, config.Service)
+	if err != nil {
+		return nil, fmt.Errorf("tickettest: %w", err)
+	}
+	s.service = service
+
+	// The same interceptors as the server started with AUTH_DISABLED=true
+	s.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(ticketservice.ActorUnaryInterceptor, ticketservice.ValidationUnaryInterceptor),
+		grpc.ChainStreamInterceptor(ticketservice.ActorStreamInterceptor),
+	)
+	ticketpb.RegisterTicketServiceServer(s.grpcServer, service)
+
+	s.listener = bufconn.Listen(bufferSize)
+	go s.grpcServer.Serve(s.listener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopEvents = cancel
+	if events != nil {
+		go events.Run(ctx, func() {
+			service.HandleEvent(ctx)
+		})
+	}
+
+	s.Conn, err = grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("tickettest: failed to connect: %w", err)
+	}
+	s.Client = ticketpb.NewTicketServiceClient(s.Conn)
+
+	return s, nil
+}
+
+// NewServer starts a ticket service that is closed when tb and its subtests complete
+func NewServer(tb testing.TB, config Config) *Server {
+	tb.Helper()
+	s, err := Start(config)
+	if err != nil {
+		tb.Fatalf("failed to start ticket service: %v", err)
+	}
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// NewClient starts a ticket service on an empty in-memory store for the
+// duration of tb and returns a client connected to it
+func NewClient(tb testing.TB) ticketpb.TicketServiceClient {
+	tb.Helper()
+	return NewServer(tb, Config{}).Client
+}
+
+// Close disconnects the client and stops the server, ending open streams
+func (s *Server) Close() {
+	s.stopEvents()
+	// Watch streams never end on their own
+	s.service.Close()
+	if s.Conn != nil {
+		s.Conn.Close()
+	}
+	s.grpcServer.Stop()
+	if s.events != nil {
+		s.events.Close()
+	}
+}
@@ -0,0 +1,253 @@
+package tickettest_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gRPC/database"
+	ticketpb "gRPC/proto/ticket"
+	"gRPC/tickettest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStartWithSQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "tickets.db")})
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := database.NewSQLiteRepository(db)
+	events := repo.NewEventListener()
+	t.Cleanup(func() { events.Close() })
+
+	s := tickettest.NewServer(t, tickettest.Config{
+		Tickets:  repo,
+		Comments: database.NewSQLiteCommentRepository(db),
+		Events:   events,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := s.Client.WatchTickets(ctx, &ticketpb.WatchTicketsRequest{})
+	if err != nil {
+		t.Fatalf("failed to watch tickets: %v", err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("failed to watch tickets: %v", err)
+	}
+
+	created, err := s.Client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{Title: "Stored in SQLite", ReporterId: "reporter-1"})
+	if err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+	id := created.GetTicket().GetId()
+
+	stored, err := s.Tickets.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("ticket %s is not in the store: %v", id, err)
+	}
+	if stored.Title != "Stored in SQLite" {
+		t.Errorf("stored title = %q, want %q", stored.Title, "Stored in SQLite")
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("failed to receive the creation: %v", err)
+	}
+	if resp.GetEvent().GetTicketId() != id || resp.GetEvent().GetType() != ticketpb.TicketEventType_TICKET_EVENT_TYPE_CREATED {
+		t.Errorf("watched event = %v, want the creation of %s", resp.GetEvent(), id)
+	}
+}
+
+// heldStore is a memory store whose event horizon can be held back, as if
+// a PostgreSQL transaction that recorded an earlier event was still in flight
+type heldStore struct {
+	*database.MemoryStore
+
+	mu      sync.Mutex
+	horizon *database.EventPosition
+}
+
+func (s *heldStore) EventHorizon(ctx context.Context) (database.EventPosition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.horizon != nil {
+		return *s.horizon, nil
+	}
+	return s.MemoryStore.EventHorizon(ctx)
+}
+
+func (s *heldStore) hold(t *testing.T) {
+	horizon, err := s.MemoryStore.EventHorizon(context.Background())
+	if err != nil {
+		t.Fatalf("EventHorizon: %v", err)
+	}
+	s.mu.Lock()
+	s.horizon = &horizon
+	s.mu.Unlock()
+}
+
+func (s *heldStore) release() {
+	s.mu.Lock()
+	s.horizon = nil
+	s.mu.Unlock()
+}
+
+func TestWatchWaitsForEventHorizon(t *testing.T) {
+	memory := database.NewMemoryStore()
+	events := memory.NewEventListener()
+	t.Cleanup(func() { events.Close() })
+
+	store := &heldStore{MemoryStore: memory}
+	s := tickettest.NewServer(t, tickettest.Config{
+		Tickets:  store,
+		Comments: memory.Comments(),
+		Events:   events,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := s.Client.WatchTickets(ctx, &ticketpb.WatchTicketsRequest{})
+	if err != nil {
+		t.Fatalf("failed to watch tickets: %v", err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("failed to watch tickets: %v", err)
+	}
+
+	received := make(chan *ticketpb.WatchTicketsResponse, 8)
+	go func() {
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			received <- resp
+		}
+	}()
+
+	store.hold(t)
+	created, err := s.Client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{Title: "Held back", ReporterId: "reporter-1"})
+	if err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+
+	select {
+	case resp := <-received:
+		t.Fatalf("received %v while the event horizon was held back", resp.GetEvent())
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// No further event is announced, so the server has to check again by itself
+	store.release()
+	select {
+	case resp := <-received:
+		if resp.GetEvent().GetTicketId() != created.GetTicket().GetId() {
+			t.Errorf("watched event = %v, want the creation of %s", resp.GetEvent(), created.GetTicket().GetId())
+		}
+	case <-ctx.Done():
+		t.Fatal("the held back event was never delivered")
+	}
+}
+
+func TestWatchReplaysTicketsAsTheyWere(t *testing.T) {
+	s := tickettest.NewServer(t, tickettest.Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	stream, err := s.Client.WatchTickets(watchCtx, &ticketpb.WatchTicketsRequest{})
+	if err != nil {
+		t.Fatalf("failed to watch tickets: %v", err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("failed to watch tickets: %v", err)
+	}
+	created, err := s.Client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{Title: "Reassigned", ReporterId: "reporter-1"})
+	if err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+	creation, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("failed to receive the creation: %v", err)
+	}
+	stopWatching()
+
+	id := created.GetTicket().GetId()
+	for _, assignee := range []string{"alice", "bob"} {
+		if _, err := s.Client.UpdateTicket(ctx, &ticketpb.UpdateTicketRequest{Id: id, AssigneeId: assignee}); err != nil {
+			t.Fatalf("failed to assign ticket to %s: %v", assignee, err)
+		}
+	}
+
+	// Both events concern alice when they happened, although the ticket is
+	// now assigned to bob
+	stream, err = s.Client.WatchTickets(ctx, &ticketpb.WatchTicketsRequest{AssigneeId: "alice", ResumeCursor: creation.GetCursor()})
+	if err != nil {
+		t.Fatalf("failed to resume watching: %v", err)
+	}
+	for _, want := range []string{"alice", "bob"} {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive the assignment to %s: %v", want, err)
+		}
+		if got := resp.GetTicket().GetAssigneeId(); got != want {
+			t.Errorf("replayed ticket is assigned to %q, want %q", got, want)
+		}
+	}
+}
+
+func TestWatchRejectsUndefinedStatuses(t *testing.T) {
+	s := tickettest.NewServer(t, tickettest.Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Neither may fall back to watching OPEN tickets
+	for _, st := range []ticketpb.TicketStatus{ticketpb.TicketStatus_TICKET_STATUS_UNSPECIFIED, 99} {
+		stream, err := s.Client.WatchTickets(ctx, &ticketpb.WatchTicketsRequest{Statuses: []ticketpb.TicketStatus{st}})
+		if err != nil {
+			t.Fatalf("failed to watch tickets: %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("watching status %d: got %v, want InvalidArgument", st, err)
+		}
+	}
+}
+
+func TestStartRejectsPartialStores(t *testing.T) {
+	if _, err := tickettest.Start(tickettest.Config{Tickets: database.NewMemoryStore()}); err == nil {
+		t.Error("Start with Tickets but no Comments succeeded")
+	}
+}
+
+func ExampleStart() {
+	s, err := tickettest.Start(tickettest.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	created, err := s.Client.CreateTicket(ctx, &ticketpb.CreateTicketRequest{Title: "Printer on fire", ReporterId: "reporter-1"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	got, err := s.Client.GetTicket(ctx, &ticketpb.GetTicketRequest{Id: created.GetTicket().GetId()})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(got.GetTicket().GetTitle(), got.GetTicket().GetStatus())
+	// Output: Printer on fire TICKET_STATUS_OPEN
+}
@@ -0,0 +1,208 @@
+// Package tlsconfig builds TLS configurations for the ticket service and its
+// clients from certificate files that are reloaded when they change on disk
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Files names the PEM files of one side of a connection
+type Files struct {
+	CertFile string // certificate presented to the peer; optional for clients
+	KeyFile  string
+	CAFile   string // CA bundle the peer's certificate must chain to
+}
+
+// loaded is a consistent snapshot of the files
+type loaded struct {
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time // latest modification time of the files
+}
+
+// Reloader serves the current certificate and CA bundle of a set of files
+type Reloader struct {
+	files   Files
+	current atomic.Pointer[loaded]
+}
+
+// NewReloader loads the files, which must form a valid certificate and key pair
+func NewReloader(files Files) (*Reloader, error) {
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, errors.New("certificate and key files must be set together")
+	}
+
+	r := &Reloader{files: files}
+	l, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(l)
+
+	return r, nil
+}
+
+func (r *Reloader) load() (*loaded, error) {
+	l := &loaded{}
+
+	modTime, err := r.modTime()
+	if err != nil {
+		return nil, err
+	}
+	l.modTime = modTime
+
+	if r.files.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		l.cert = &cert
+	}
+
+	if r.files.CAFile != "" {
+		pem, err := os.ReadFile(r.files.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		l.pool = x509.NewCertPool()
+		if !l.pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", r.files.CAFile)
+		}
+	}
+
+	return l, nil
+}
+
+// modTime returns the latest modification time of the configured files
+func (r *Reloader) modTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.files.CertFile, r.files.KeyFile, r.files.CAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Watch reloads the files whenever one of them changes, checking every
+// interval until ctx is cancelled. New connections use the new files;
+// established ones are unaffected. A failed reload keeps the previous files.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, err := r.modTime()
+		if err != nil {
+			log.Printf("TLS: Error checking certificate files: %v", err)
+			continue
+		}
+		if !modTime.After(r.current.Load().modTime) {
+			continue
+		}
+
+		l, err := r.load()
+		if err != nil {
+			// Certificate and key are often replaced one after the other, so a
+			// mismatched pair is retried on the next tick
+			log.Printf("TLS: Keeping previous certificates, reload failed: %v", err)
+			continue
+		}
+		r.current.Store(l)
+		log.Printf("🔄 Reloaded TLS certificates from %s", r.files.CertFile)
+	}
+}
+
+// ServerConfig returns a server configuration presenting the current
+// certificate. With a CA file, client certificates are checked against it
+// according to clientAuth, e.g. tls.RequireAndVerifyClientCert for mutual TLS.
+func (r *Reloader) ServerConfig(clientAuth tls.ClientAuthType) (*tls.Config, error) {
+	if r.files.CertFile == "" {
+		return nil, errors.New("a server certificate is required")
+	}
+	if clientAuth != tls.NoClientCert && r.files.CAFile == "" {
+		return nil, errors.New("verifying client certificates requires a CA file")
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			l := r.current.Load()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*l.cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    l.pool,
+			}, nil
+		},
+	}, nil
+}
+
+// ClientConfig returns a client configuration verifying the server against
+// the current CA bundle, or the system roots without a CA file, and
+// presenting the current client certificate when the server asks for one.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := r.current.Load().cert; cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil // no certificate to offer
+		},
+	}
+	if r.files.CAFile == "" {
+		return config
+	}
+
+	// RootCAs is fixed once the config is in use, so the standard verification
+	// is replaced with one against the CA bundle loaded at handshake time.
+	// Unlike the standard one it only sees the server name sent in SNI, which
+	// leaves out IP addresses; those must be passed as serverName.
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		name := cs.ServerName
+		if name == "" {
+			name = serverName
+		}
+		if name == "" {
+			return errors.New("no server name to verify the server certificate against")
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("server presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			DNSName:       name,
+			Roots:         r.current.Load().pool,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+	return config
+}
@@ -0,0 +1,109 @@
+// Package tracing sets up OpenTelemetry tracing for the ticket service and
+// its clients, exporting spans over OTLP or to stdout or a local file
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters supported by Setup
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Config selects where spans are exported
+type Config struct {
+	ServiceName string
+	Exporter    string // ExporterNone, ExporterOTLP or ExporterStdout; empty means none
+	File        string // with ExporterStdout, append spans to this file instead of stdout
+}
+
+// FromEnv reads OTEL_TRACES_EXPORTER and OTEL_TRACES_FILE. The OTLP endpoint
+// is read by the exporter itself from the standard OTEL_EXPORTER_OTLP_* variables.
+func FromEnv(serviceName string) Config {
+	return Config{
+		ServiceName: serviceName,
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		File:        os.Getenv("OTEL_TRACES_FILE"),
+	}
+}
+
+// Setup installs the global tracer provider and the W3C trace context and
+// baggage propagators, and returns a function that flushes pending spans.
+// Propagation is installed even when no exporter is configured, so trace
+// context still flows from callers to downstream services.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var out io.Closer
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err := otlptracegrpc.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = exp
+	case ExporterStdout:
+		var w io.Writer = os.Stdout
+		if config.File != "" {
+			f, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open trace file: %w", err)
+			}
+			w, out = f, f
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s",
+			config.Exporter, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+
+	// Later options win, so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+	// override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", config.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if out != nil {
+			err = errors.Join(err, out.Close())
+		}
+		return err
+	}, nil
+}
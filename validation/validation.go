@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	ticketpb "gRPC/proto/ticket"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Violation describes a single field that failed validation
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Errors collects every violation found in a message
+type Errors []Violation
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, v := range e {
+		parts[i] = v.Field + ": " + v.Description
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+// Invalid returns an Errors holding a single violation
+func Invalid(field, description string) Errors {
+	return Errors{{Field: field, Description: description}}
+}
+
+// Validate checks a message, and any messages nested in it, against the
+// (ticket.rules) options declared on their fields
+func Validate(msg proto.Message) error {
+	var errs Errors
+	validateMessage(msg.ProtoReflect(), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateMessage(m protoreflect.Message, prefix string, errs *Errors) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+
+		if rules := fieldRules(fd); rules != nil {
+			validateField(m, fd, path, rules, errs)
+		}
+
+		// Recurse into set singular messages so nested rules apply too
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() && m.Has(fd) {
+			validateMessage(m.Get(fd).Message(), path+".", errs)
+		}
+	}
+}
+
+// fieldRules returns the rules declared on a field, or nil if it has none
+func fieldRules(fd protoreflect.FieldDescriptor) *ticketpb.FieldRules {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil || !proto.HasExtension(opts, ticketpb.E_Rules) {
+		return nil
+	}
+	return proto.GetExtension(opts, ticketpb.E_Rules).(*ticketpb.FieldRules)
+}
+
+func validateField(m protoreflect.Message, fd protoreflect.FieldDescriptor, path string, rules *ticketpb.FieldRules, errs *Errors) {
+	add := func(format string, args ...interface{}) {
+		*errs = append(*errs, Violation{Field: path, Description: fmt.Sprintf(format, args...)})
+	}
+
+	if fd.IsList() {
+		list := m.Get(fd).List()
+		if rules.Required && list.Len() == 0 {
+			add("is required")
+		}
+		if rules.MaxItems > 0 && uint32(list.Len()) > rules.MaxItems {
+			add("must have at most %d items", rules.MaxItems)
+		}
+
+		seen := make(map[interface{}]bool, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			v := list.Get(i)
+			if fd.Kind() == protoreflect.EnumKind && rules.DefinedOnly && fd.Enum().Values().ByNumber(v.Enum()) == nil {
+				*errs = append(*errs, Violation{Field: fmt.Sprintf("%s[%d]", path, i), Description: fmt.Sprintf("unknown value %d", v.Enum())})
+			}
+			if rules.UniqueItems {
+				if seen[v.Interface()] {
+					add("item %q is repeated", v.String())
+				}
+				seen[v.Interface()] = true
+			}
+			if fd.Kind() == protoreflect.StringKind {
+				n := uint32(utf8.RuneCountInString(v.String()))
+				if rules.ItemMinLen > 0 && n < rules.ItemMinLen {
+					add("items must be at least %d characters", rules.ItemMinLen)
+				}
+				if rules.ItemMaxLen > 0 && n > rules.ItemMaxLen {
+					add("items must be at most %d characters", rules.ItemMaxLen)
+				}
+			}
+		}
+		return
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := m.Get(fd).String()
+		n := uint32(utf8.RuneCountInString(s))
+		if rules.Required && strings.TrimSpace(s) == "" {
+			add("is required")
+			return
+		}
+		if rules.MinLen > 0 && n < rules.MinLen {
+			add("must be at least %d characters", rules.MinLen)
+		}
+		if rules.MaxLen > 0 && n > rules.MaxLen {
+			add("must be at most %d characters", rules.MaxLen)
+		}
+	case protoreflect.EnumKind:
+		v := m.Get(fd).Enum()
+		if rules.DefinedOnly && fd.Enum().Values().ByNumber(v) == nil {
+			add("unknown value %d", v)
+			return
+		}
+		if rules.Required && v == 0 {
+			add("is required")
+		}
+	case protoreflect.MessageKind:
+		if rules.Required && !m.Has(fd) {
+			add("is required")
+		}
+	}
+}
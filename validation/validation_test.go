@@ -0,0 +1,271 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	ticketpb "gRPC/proto/ticket"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// sampleDescriptor builds a message with one field per rule, so that every
+// rule can be checked on its own
+func sampleDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	rules := func(r *ticketpb.FieldRules) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, ticketpb.E_Rules, r)
+		return opts
+	}
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type, repeated bool, typeName string, r *ticketpb.FieldRules) *descriptorpb.FieldDescriptorProto {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  label.Enum(),
+			Type:   kind.Enum(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		if r != nil {
+			fd.Options = rules(r)
+		}
+		return fd
+	}
+
+	const (
+		str  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		enum = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		msg  = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("validation_test.proto"),
+		Package: proto.String("validationtest"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Color"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("COLOR_RED"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Nested"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, str, false, "", &ticketpb.FieldRules{Required: true}),
+				},
+			},
+			{
+				Name: proto.String("Sample"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("required_string", 1, str, false, "", &ticketpb.FieldRules{Required: true}),
+					field("min_len", 2, str, false, "", &ticketpb.FieldRules{MinLen: 3}),
+					field("max_len", 3, str, false, "", &ticketpb.FieldRules{MaxLen: 3}),
+					field("required_enum", 4, enum, false, ".validationtest.Color", &ticketpb.FieldRules{Required: true}),
+					field("defined_enum", 5, enum, false, ".validationtest.Color", &ticketpb.FieldRules{DefinedOnly: true}),
+					field("required_message", 6, msg, false, ".validationtest.Nested", &ticketpb.FieldRules{Required: true}),
+					field("required_list", 7, str, true, "", &ticketpb.FieldRules{Required: true}),
+					field("max_items", 8, str, true, "", &ticketpb.FieldRules{MaxItems: 2}),
+					field("unique_items", 9, str, true, "", &ticketpb.FieldRules{UniqueItems: true}),
+					field("item_len", 10, str, true, "", &ticketpb.FieldRules{ItemMinLen: 2, ItemMaxLen: 3}),
+					field("defined_enums", 11, enum, true, ".validationtest.Color", &ticketpb.FieldRules{DefinedOnly: true}),
+					field("nested", 12, msg, false, ".validationtest.Nested", nil),
+				},
+			},
+		},
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+	return fd.Messages().ByName("Sample")
+}
+
+// validSample returns a Sample that satisfies every rule
+func validSample(md protoreflect.MessageDescriptor) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	set := func(name string, v protoreflect.Value) {
+		m.Set(md.Fields().ByName(protoreflect.Name(name)), v)
+	}
+	set("required_string", protoreflect.ValueOfString("set"))
+	set("min_len", protoreflect.ValueOfString("set"))
+	set("required_enum", protoreflect.ValueOfEnum(1))
+
+	nested := dynamicpb.NewMessage(md.Fields().ByName("required_message").Message())
+	nested.Set(nested.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("set"))
+	set("required_message", protoreflect.ValueOfMessage(nested))
+
+	list := m.Mutable(md.Fields().ByName("required_list")).List()
+	list.Append(protoreflect.ValueOfString("item"))
+	return m
+}
+
+func appendStrings(m *dynamicpb.Message, name string, items ...string) {
+	list := m.Mutable(m.Descriptor().Fields().ByName(protoreflect.Name(name))).List()
+	for _, item := range items {
+		list.Append(protoreflect.ValueOfString(item))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	md := sampleDescriptor(t)
+	field := func(name string) protoreflect.FieldDescriptor {
+		return md.Fields().ByName(protoreflect.Name(name))
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *dynamicpb.Message)
+		want   []string // violated fields
+	}{
+		{
+			name:   "valid",
+			modify: func(m *dynamicpb.Message) {},
+		},
+		{
+			name:   "required string missing",
+			modify: func(m *dynamicpb.Message) { m.Clear(field("required_string")) },
+			want:   []string{"required_string"},
+		},
+		{
+			name:   "required string blank",
+			modify: func(m *dynamicpb.Message) { m.Set(field("required_string"), protoreflect.ValueOfString("  ")) },
+			want:   []string{"required_string"},
+		},
+		{
+			name:   "string too short",
+			modify: func(m *dynamicpb.Message) { m.Set(field("min_len"), protoreflect.ValueOfString("ab")) },
+			want:   []string{"min_len"},
+		},
+		{
+			name:   "string with a minimum length empty",
+			modify: func(m *dynamicpb.Message) { m.Clear(field("min_len")) },
+			want:   []string{"min_len"},
+		},
+		{
+			name:   "string long enough in characters",
+			modify: func(m *dynamicpb.Message) { m.Set(field("min_len"), protoreflect.ValueOfString("äöü")) },
+		},
+		{
+			name:   "string too long",
+			modify: func(m *dynamicpb.Message) { m.Set(field("max_len"), protoreflect.ValueOfString("abcd")) },
+			want:   []string{"max_len"},
+		},
+		{
+			name:   "string short enough in characters",
+			modify: func(m *dynamicpb.Message) { m.Set(field("max_len"), protoreflect.ValueOfString("äöü")) },
+		},
+		{
+			name:   "required enum unspecified",
+			modify: func(m *dynamicpb.Message) { m.Clear(field("required_enum")) },
+			want:   []string{"required_enum"},
+		},
+		{
+			name:   "enum undefined",
+			modify: func(m *dynamicpb.Message) { m.Set(field("defined_enum"), protoreflect.ValueOfEnum(99)) },
+			want:   []string{"defined_enum"},
+		},
+		{
+			name:   "required message missing",
+			modify: func(m *dynamicpb.Message) { m.Clear(field("required_message")) },
+			want:   []string{"required_message"},
+		},
+		{
+			name: "nested message invalid",
+			modify: func(m *dynamicpb.Message) {
+				m.Mutable(field("nested")).Message().Set(field("nested").Message().Fields().ByName("name"), protoreflect.ValueOfString(""))
+			},
+			want: []string{"nested.name"},
+		},
+		{
+			name:   "required list empty",
+			modify: func(m *dynamicpb.Message) { m.Clear(field("required_list")) },
+			want:   []string{"required_list"},
+		},
+		{
+			name:   "too many items",
+			modify: func(m *dynamicpb.Message) { appendStrings(m, "max_items", "a", "b", "c") },
+			want:   []string{"max_items"},
+		},
+		{
+			name:   "enough items",
+			modify: func(m *dynamicpb.Message) { appendStrings(m, "max_items", "a", "b") },
+		},
+		{
+			name:   "repeated item",
+			modify: func(m *dynamicpb.Message) { appendStrings(m, "unique_items", "a", "b", "a") },
+			want:   []string{"unique_items"},
+		},
+		{
+			name:   "item too short",
+			modify: func(m *dynamicpb.Message) { appendStrings(m, "item_len", "ab", "a") },
+			want:   []string{"item_len"},
+		},
+		{
+			name:   "item too long",
+			modify: func(m *dynamicpb.Message) { appendStrings(m, "item_len", "abcd", "abc") },
+			want:   []string{"item_len"},
+		},
+		{
+			name: "enum items undefined",
+			modify: func(m *dynamicpb.Message) {
+				list := m.Mutable(field("defined_enums")).List()
+				for _, v := range []protoreflect.EnumNumber{1, 99, 0, 7} {
+					list.Append(protoreflect.ValueOfEnum(v))
+				}
+			},
+			want: []string{"defined_enums[1]", "defined_enums[3]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := validSample(md)
+			tt.modify(m)
+
+			err := Validate(m)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Validate() = %v, want Errors", err)
+			}
+			var fields []string
+			for _, v := range errs {
+				fields = append(fields, v.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("violated fields = %v, want %v (%v)", fields, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateWatchStatuses(t *testing.T) {
+	// A repeated enum is held to defined_only like a singular one
+	err := Validate(&ticketpb.WatchTicketsRequest{Statuses: []ticketpb.TicketStatus{ticketpb.TicketStatus_TICKET_STATUS_OPEN, 99}})
+	if err == nil || !strings.Contains(err.Error(), "statuses[1]: unknown value 99") {
+		t.Errorf("Validate() = %v, want statuses[1] rejected", err)
+	}
+	if err := Validate(&ticketpb.TransitionTicketRequest{Id: "t-1", Status: 99}); err == nil {
+		t.Error("Validate() accepted an unknown status")
+	}
+}